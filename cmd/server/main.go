@@ -0,0 +1,184 @@
+// main.go is the entry point of the Feast Friends API
+// it loads the config, connects to supabase and postgres, builds the router
+// wraps it with the global middleware and starts the http server
+// on SIGINT/SIGTERM it stops accepting requests, waits for in flight ones and closes the db pool
+
+package main
+
+import (
+	"context"
+	"errors"
+	"feast-friends-api/internal/auth"
+	"feast-friends-api/internal/config"
+	"feast-friends-api/internal/health"
+	"feast-friends-api/internal/metrics"
+	"feast-friends-api/internal/middleware"
+	"feast-friends-api/internal/realtime"
+	"feast-friends-api/internal/repository"
+	"feast-friends-api/internal/tracing"
+	"feast-friends-api/internal/utils"
+	"feast-friends-api/pkg/logger"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+func main() {
+	cfg := config.Get()
+
+	utils.VerifyJWTConfig()
+
+	// connect to supabase and the db before accepting any traffic
+	if err := utils.Connection(); err != nil {
+		logger.Error("failed to connect to dependencies: %v", err)
+		os.Exit(1)
+	}
+
+	// ctx is cancelled when we receive an interrupt or terminate signal
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// spans go to the exporter picked by TRACING_EXPORTER, none only passes traceparent on
+	shutdownTracing, err := tracing.Setup(ctx, cfg)
+	if err != nil {
+		logger.Error("invalid tracing config: %v", err)
+		utils.CloseConnections()
+		os.Exit(1)
+	}
+
+	hub := newHub(ctx, cfg)
+
+	// /metrics shows routes, pool and runtime internals, it is never served without a token
+	if cfg.Metrics.Enabled && cfg.Metrics.Token == "" {
+		logger.Error("METRICS_ENABLED requires METRICS_TOKEN")
+		utils.CloseConnections()
+		os.Exit(1)
+	}
+	// pgxpool stats are read on every scrape of /metrics
+	metrics.RegisterPool(utils.DB)
+
+	// session tokens issued by the api, AuthMiddleware also checks the revocation list through it
+	tokens, err := auth.NewTokenServiceFromConfig(cfg, repository.NewPostgresSessionRepository(utils.DB))
+	if err != nil {
+		logger.Error("invalid JWT config: %v", err)
+		utils.CloseConnections()
+		os.Exit(1)
+	}
+	// refreshed tokens and supabase tokens get the roles stored on the profile
+	tokens.UseRoles(repository.NewPostgresProfileRepository(utils.DB))
+	middleware.UseTokenService(tokens)
+
+	// supabase auth owns the credentials, the service key client is only used to delete users
+	identity := auth.NewSupabaseIdentity(
+		utils.NewAuthClient(cfg.Supabase.AKey),
+		utils.NewAuthClient(cfg.Supabase.Skey).WithToken(cfg.Supabase.Skey),
+	)
+
+	// the client ip in the logs and the rate limits comes from X-Forwarded-For only behind these proxies
+	if err := middleware.UseTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		logger.Error("invalid TRUSTED_PROXIES: %v", err)
+		utils.CloseConnections()
+		os.Exit(1)
+	}
+
+	// "*" with credentials would let every site call the api as the logged in user
+	corsPolicy := middleware.CORSPolicyFromConfig(cfg)
+	if err := corsPolicy.Validate(); err != nil {
+		logger.Error("invalid CORS config: %v", err)
+		utils.CloseConnections()
+		os.Exit(1)
+	}
+
+	// rate limit buckets are kept in process, each replica limits on its own
+	var limiter middleware.RateLimitStore
+	if cfg.RateLimit.Enabled {
+		limiter = middleware.NewMemoryRateLimitStore()
+	}
+
+	// panics are logged with the request id and, when CRASH_REPORT_FILE is set, written to that file
+	var crashes middleware.CrashReporter
+	if cfg.Logging.CrashFile != "" {
+		crashes = middleware.NewFileCrashReporter(cfg.Logging.CrashFile)
+	}
+
+	// /health/ready reports these dependencies, it starts failing once shutdown begins
+	checker := health.NewChecker(cfg.Health.Timeout, cfg.Health.CacheTTL,
+		health.Check{Name: "database", Run: utils.PingDB},
+		health.Check{Name: "supabase", Run: utils.PingSupabase},
+	)
+
+	// global middleware, Logs runs first so every request gets a request id, Tracing second so the span
+	// carries it and Recover third so panics are logged with it and the span sees the 500. the global rate limit runs before the router so it only knows the client ip.
+	// Routes sits right around the mux to hand the matched pattern back to Logs for the metrics
+	router := middleware.Routes(newRouter(utils.DB, hub, tokens, identity, limiter, checker))
+	cors := middleware.CORS(corsPolicy)
+	global := middleware.RateLimit(limiter, "global", cfg.RateLimit.Global)
+	handler := middleware.Logs(middleware.Tracing(middleware.Recover(crashes)(cors(global(router)))))
+
+	srv := &http.Server{
+		Addr:         ":" + cfg.Server.Port,
+		Handler:      handler,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+		IdleTimeout:  cfg.Server.IdleTimeout,
+	}
+	// realtime streams never go idle, closing the hub ends them so Shutdown does not wait for the timeout
+	srv.RegisterOnShutdown(hub.Close)
+
+	serverErr := make(chan error, 1)
+	go func() {
+		logger.Info("server listening on port %s (%s)", cfg.Server.Port, cfg.Environment)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+		close(serverErr)
+	}()
+
+	select {
+	case err := <-serverErr:
+		if err != nil {
+			logger.Error("server failed: %v", err)
+			utils.CloseConnections()
+			os.Exit(1)
+		}
+	case <-ctx.Done():
+		logger.Info("shutdown signal received, draining connections")
+	}
+
+	// fail the readiness probe so load balancers stop routing new requests here, a second signal
+	// during the delay stops the server right away
+	stop()
+	checker.Drain()
+	if cfg.Server.DrainDelay > 0 {
+		logger.Info("readiness probe failing, waiting %s before shutting down", cfg.Server.DrainDelay)
+		time.Sleep(cfg.Server.DrainDelay)
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		logger.Error("graceful shutdown failed: %v", err)
+	}
+
+	// flush the spans still buffered by the exporter
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		logger.Error("failed to flush traces: %v", err)
+	}
+
+	utils.CloseConnections()
+	logger.Info("server stopped")
+}
+
+// newHub builds the realtime hub selected by REALTIME_HUB, the postgres listener runs until ctx is cancelled
+func newHub(ctx context.Context, cfg *config.Config) realtime.Hub {
+	if cfg.Realtime.Hub == "postgres" {
+		hub := realtime.NewPostgresHub(utils.DB, cfg.Realtime.Channel)
+		go hub.Run(ctx)
+		logger.Info("realtime events are fanned out through postgres channel %s", cfg.Realtime.Channel)
+		return hub
+	}
+	return realtime.NewLocalHub()
+}
@@ -0,0 +1,146 @@
+// routes.go registers every endpoint the api exposes on a standard library ServeMux
+// public routes are registered as they are, protected routes are wrapped with AuthMiddleware
+// and public routes that personalise their response (liked_by_me) are wrapped with OptionalAuth
+
+package main
+
+import (
+	"crypto/subtle"
+	"errors"
+	"feast-friends-api/internal/auth"
+	"feast-friends-api/internal/config"
+	"feast-friends-api/internal/handlers"
+	"feast-friends-api/internal/health"
+	"feast-friends-api/internal/metrics"
+	"feast-friends-api/internal/middleware"
+	"feast-friends-api/internal/realtime"
+	"feast-friends-api/internal/repository"
+	"feast-friends-api/internal/services"
+	"feast-friends-api/internal/utils"
+	"net/http"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// newRouter builds the repositories, handlers and the mux with all the app routes
+// limiter holds the rate limit buckets, nil when rate limiting is disabled
+// checker runs the dependency checks of the readiness probe
+func newRouter(db *pgxpool.Pool, hub realtime.Hub, tokens *auth.TokenService, identity auth.IdentityProvider, limiter middleware.RateLimitStore, checker *health.Checker) *http.ServeMux {
+	mux := http.NewServeMux()
+	limits := config.Get().RateLimit
+
+	// shorthand for routes that need an authenticated user
+	protected := func(h http.HandlerFunc) http.Handler {
+		return middleware.AuthMiddleware(h)
+	}
+	// shorthand for public routes that personalise the response when a token is sent
+	optional := func(h http.HandlerFunc) http.Handler {
+		return middleware.OptionalAuth(h)
+	}
+	// shorthand for routes only admins can use
+	admin := func(h http.HandlerFunc) http.Handler {
+		return middleware.AuthMiddleware(middleware.RequireRole(auth.RoleAdmin)(h))
+	}
+	// shorthand for rate limited handlers, inside protected(...) the buckets are per user, otherwise per ip
+	throttle := func(name string, limit config.RateLimit, h http.HandlerFunc) http.HandlerFunc {
+		return middleware.RateLimit(limiter, name, limit)(h).ServeHTTP
+	}
+
+	postRepo := repository.NewPostgresPostRepository(db)
+	socialRepo := repository.NewPostgresSocialRepository(db)
+
+	posts := handlers.NewPostHandler(postRepo, socialRepo)
+	social := handlers.NewSocialHandler(socialRepo, postRepo)
+	feed := handlers.NewFeedHandler(repository.NewPostgresFeedRepository(db))
+	comments := handlers.NewCommentHandler(repository.NewPostgresCommentRepository(db))
+	events := handlers.NewEventHandler(repository.NewPostgresEventRepository(db), services.NewRealtimeNotifier(hub))
+	messageRepo := repository.NewPostgresMessageRepository(db)
+	messages := handlers.NewMessageHandler(messageRepo, hub)
+	stream := handlers.NewRealtimeHandler(hub, messageRepo, middleware.CORSPolicyFromConfig(config.Get()).AllowsOrigin)
+	profileRepo := repository.NewPostgresProfileRepository(db)
+	sessions := handlers.NewAuthHandler(tokens, identity, profileRepo)
+	roles := handlers.NewRoleHandler(profileRepo, tokens)
+	probes := handlers.NewHealthHandler(checker)
+
+	// public routes
+	mux.HandleFunc("GET /health", handlers.Health)
+	mux.HandleFunc("GET /health/live", probes.Live)
+	mux.HandleFunc("GET /health/ready", probes.Ready)
+	mux.HandleFunc("POST /api/v1/auth/signup", throttle("auth", limits.Auth, sessions.SignUp))
+	mux.HandleFunc("POST /api/v1/auth/login", throttle("auth", limits.Auth, sessions.Login))
+	mux.HandleFunc("POST /api/v1/auth/refresh", throttle("auth", limits.Auth, sessions.Refresh))
+	mux.HandleFunc("POST /api/v1/auth/password/forgot", throttle("auth", limits.Auth, sessions.ForgotPassword))
+	mux.HandleFunc("POST /api/v1/auth/password/reset", throttle("auth", limits.Auth, sessions.ResetPassword))
+	mux.Handle("GET /api/v1/posts", optional(posts.List))
+	mux.Handle("GET /api/v1/posts/{id}", optional(posts.Get))
+	mux.HandleFunc("GET /api/v1/posts/{id}/comments", comments.ListByPost)
+	mux.HandleFunc("GET /api/v1/comments/{id}/replies", comments.ListReplies)
+	mux.HandleFunc("GET /api/v1/comments/{id}/edits", comments.ListEdits)
+	mux.HandleFunc("GET /api/v1/events", events.List)
+	mux.HandleFunc("GET /api/v1/events/{id}", events.Get)
+	mux.Handle("GET /api/v1/users/{id}/posts", optional(posts.ListByUser))
+	mux.HandleFunc("GET /api/v1/users/{id}/followers", social.Followers)
+	mux.HandleFunc("GET /api/v1/users/{id}/following", social.Following)
+
+	// protected routes
+	mux.Handle("GET /api/v1/me", protected(me))
+	mux.Handle("POST /api/v1/auth/logout", protected(sessions.Logout))
+	mux.Handle("GET /api/v1/feed/following", protected(feed.Following))
+	mux.Handle("POST /api/v1/posts", protected(throttle("posts", limits.Posts, posts.Create)))
+	mux.Handle("PUT /api/v1/posts/{id}", protected(posts.Update))
+	mux.Handle("DELETE /api/v1/posts/{id}", protected(posts.Delete))
+	mux.Handle("POST /api/v1/posts/{id}/like", protected(social.Like))
+	mux.Handle("DELETE /api/v1/posts/{id}/like", protected(social.Unlike))
+	mux.Handle("POST /api/v1/posts/{id}/comments", protected(throttle("posts", limits.Posts, comments.Create)))
+	mux.Handle("PUT /api/v1/comments/{id}", protected(comments.Update))
+	mux.Handle("DELETE /api/v1/comments/{id}", protected(comments.Delete))
+	mux.Handle("POST /api/v1/events", protected(throttle("posts", limits.Posts, events.Create)))
+	mux.Handle("PUT /api/v1/events/{id}", protected(events.Update))
+	mux.Handle("POST /api/v1/events/{id}/cancel", protected(events.Cancel))
+	mux.Handle("PUT /api/v1/events/{id}/rsvp", protected(events.RSVP))
+	mux.Handle("GET /api/v1/events/{id}/attendees", protected(events.Attendees))
+	mux.Handle("GET /api/v1/events/{id}/waitlist", protected(events.Waitlist))
+	mux.Handle("PUT /api/v1/events/{id}/waitlist", protected(events.ReorderWaitlist))
+	mux.Handle("GET /api/v1/conversations", protected(messages.Inbox))
+	mux.Handle("POST /api/v1/conversations", protected(throttle("messages", limits.Messages, messages.Open)))
+	mux.Handle("GET /api/v1/conversations/{id}/messages", protected(messages.List))
+	mux.Handle("POST /api/v1/conversations/{id}/messages", protected(throttle("messages", limits.Messages, messages.Send)))
+	mux.Handle("POST /api/v1/conversations/{id}/read", protected(messages.MarkRead))
+	mux.Handle("POST /api/v1/conversations/{id}/typing", protected(throttle("typing", limits.Messages, messages.Typing)))
+	// browsers cannot send headers on WebSocket/EventSource requests so the token may come as ?access_token=
+	mux.Handle("GET /api/v1/realtime", middleware.TokenFromQuery(protected(stream.Stream)))
+	mux.Handle("POST /api/v1/users/{id}/follow", protected(social.Follow))
+	mux.Handle("DELETE /api/v1/users/{id}/follow", protected(social.Unfollow))
+
+	// prometheus scrape endpoint
+	if cfg := config.Get().Metrics; cfg.Enabled {
+		mux.Handle("GET /metrics", metricsAuth(cfg.Token, metrics.Handler()))
+	}
+
+	// admin routes
+	mux.Handle("GET /api/v1/users/{id}/roles", admin(roles.Get))
+	mux.Handle("PUT /api/v1/users/{id}/roles", admin(roles.Set))
+
+	return mux
+}
+
+// metricsAuth only lets scrapers that send the token through, main refuses to start without one
+func metricsAuth(token string, next http.Handler) http.Handler {
+	expected := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token == "" || subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			utils.WriteJSON(w, http.StatusUnauthorized, utils.ErrorResponse("Invalid metrics token", errors.New("invalid metrics token"), http.StatusUnauthorized))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// me returns the id and roles of the authenticated user, handy to check a token works
+func me(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	utils.WriteJSON(w, http.StatusOK, utils.SuccessResponse(map[string]interface{}{
+		"user_id": userID,
+		"roles":   middleware.RolesFromContext(r.Context()),
+	}, "authenticated"))
+}
@@ -0,0 +1,48 @@
+// Package auth issues and verifies the session tokens of the api.
+// supabase proves who the user is (password login), the TokenService then hands out a short lived
+// access token and a refresh token that is rotated on every use.
+package auth
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dayUnits matches the day and week units time.ParseDuration does not know about
+var dayUnits = regexp.MustCompile(`(\d+(?:\.\d+)?)([dw])`)
+
+// ParseDuration parses the durations used in the config, on top of the time.ParseDuration
+// units it accepts days and weeks ("7d", "1.5d", "2w", "1d12h") and ignores case ("24H", "7D")
+func ParseDuration(s string) (time.Duration, error) {
+	value := strings.ToLower(strings.TrimSpace(s))
+
+	var convErr error
+	value = dayUnits.ReplaceAllStringFunc(value, func(part string) string {
+		m := dayUnits.FindStringSubmatch(part)
+		n, err := strconv.ParseFloat(m[1], 64)
+		if err != nil {
+			convErr = err
+			return part
+		}
+		hours := n * 24
+		if m[2] == "w" {
+			hours *= 7
+		}
+		return strconv.FormatFloat(hours, 'f', -1, 64) + "h"
+	})
+	if convErr != nil {
+		return 0, fmt.Errorf("invalid duration %q: %w", s, convErr)
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("duration %q must be positive", s)
+	}
+	return d, nil
+}
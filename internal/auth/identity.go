@@ -0,0 +1,48 @@
+// identity.go defines the IdentityProvider, the service that owns email and password credentials
+// supabase auth (gotrue) is the provider in production, passwords never touch our database
+
+package auth
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+// errors returned by IdentityProvider implementations
+var (
+	// ErrEmailTaken is returned when signing up with an email that already has an account
+	ErrEmailTaken = errors.New("email already registered")
+	// ErrInvalidCredentials is returned when the email and password do not match
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	// ErrEmailNotConfirmed is returned when logging in before confirming the email
+	ErrEmailNotConfirmed = errors.New("email not confirmed")
+	// ErrPasswordRejected is returned when the provider refuses a new password (e.g. same as the old one)
+	ErrPasswordRejected = errors.New("password rejected")
+)
+
+// SignUpResult is the account created by SignUp
+type SignUpResult struct {
+	UserID uuid.UUID
+	// Confirmed is false when the provider sends a confirmation email before the account can log in
+	Confirmed bool
+}
+
+// IdentityProvider creates accounts and checks credentials
+type IdentityProvider interface {
+	// SignUp creates the account, returns ErrEmailTaken when the email is registered
+	SignUp(ctx context.Context, email, password string) (*SignUpResult, error)
+	// SignIn checks the credentials and returns the user id
+	// returns ErrInvalidCredentials or ErrEmailNotConfirmed
+	SignIn(ctx context.Context, email, password string) (uuid.UUID, error)
+	// SignOut ends the provider sessions of an access token the provider issued
+	SignOut(ctx context.Context, accessToken string) error
+	// SendPasswordReset emails a recovery link, unknown emails are not reported
+	SendPasswordReset(ctx context.Context, email string) error
+	// ResetPassword sets a new password using the token of the recovery link and returns the user id
+	// returns ErrInvalidToken for bad or expired recovery tokens and ErrPasswordRejected
+	ResetPassword(ctx context.Context, recoveryToken, password string) (uuid.UUID, error)
+	// DeleteUser removes the account, used to roll back a sign up that could not be completed
+	DeleteUser(ctx context.Context, id uuid.UUID) error
+}
@@ -0,0 +1,143 @@
+// identity_memory.go is an in-memory IdentityProvider used by tests and local experiments
+// accounts are confirmed right away unless RequireConfirmation is set, recovery tokens are handed
+// out by SendPasswordReset through LastRecoveryToken instead of an email
+
+package auth
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// memoryAccount is one account of the MemoryIdentity
+type memoryAccount struct {
+	id        uuid.UUID
+	hash      []byte
+	confirmed bool
+}
+
+// MemoryIdentity keeps accounts in memory with bcrypt hashed passwords
+type MemoryIdentity struct {
+	// RequireConfirmation makes new accounts unable to log in until Confirm is called
+	RequireConfirmation bool
+
+	mu       sync.Mutex
+	accounts map[string]*memoryAccount // lower case email -> account
+	recovery map[string]uuid.UUID      // recovery token -> user id
+	last     string
+}
+
+// make sure the implementation satisfies the interface at compile time
+var _ IdentityProvider = (*MemoryIdentity)(nil)
+
+// NewMemoryIdentity creates a provider without accounts
+func NewMemoryIdentity() *MemoryIdentity {
+	return &MemoryIdentity{accounts: make(map[string]*memoryAccount), recovery: make(map[string]uuid.UUID)}
+}
+
+// SignUp creates the account
+func (m *MemoryIdentity) SignUp(ctx context.Context, email, password string) (*SignUpResult, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := strings.ToLower(email)
+	if _, ok := m.accounts[key]; ok {
+		return nil, ErrEmailTaken
+	}
+	account := &memoryAccount{id: uuid.New(), hash: hash, confirmed: !m.RequireConfirmation}
+	m.accounts[key] = account
+	return &SignUpResult{UserID: account.id, Confirmed: account.confirmed}, nil
+}
+
+// Confirm marks the account of the email as confirmed
+func (m *MemoryIdentity) Confirm(email string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if account, ok := m.accounts[strings.ToLower(email)]; ok {
+		account.confirmed = true
+	}
+}
+
+// SignIn compares the password with the stored hash
+func (m *MemoryIdentity) SignIn(ctx context.Context, email, password string) (uuid.UUID, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	account, ok := m.accounts[strings.ToLower(email)]
+	if !ok || bcrypt.CompareHashAndPassword(account.hash, []byte(password)) != nil {
+		return uuid.Nil, ErrInvalidCredentials
+	}
+	if !account.confirmed {
+		return uuid.Nil, ErrEmailNotConfirmed
+	}
+	return account.id, nil
+}
+
+// SignOut has nothing to end, the memory provider does not issue tokens
+func (m *MemoryIdentity) SignOut(ctx context.Context, accessToken string) error {
+	return nil
+}
+
+// SendPasswordReset creates a recovery token for known emails
+func (m *MemoryIdentity) SendPasswordReset(ctx context.Context, email string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if account, ok := m.accounts[strings.ToLower(email)]; ok {
+		m.last = uuid.NewString()
+		m.recovery[m.last] = account.id
+	}
+	return nil
+}
+
+// LastRecoveryToken returns the token created by the last SendPasswordReset for a known email
+func (m *MemoryIdentity) LastRecoveryToken() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.last
+}
+
+// ResetPassword replaces the password, recovery tokens work once
+func (m *MemoryIdentity) ResetPassword(ctx context.Context, recoveryToken, password string) (uuid.UUID, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id, ok := m.recovery[recoveryToken]
+	if !ok {
+		return uuid.Nil, ErrInvalidToken
+	}
+	delete(m.recovery, recoveryToken)
+	for _, account := range m.accounts {
+		if account.id == id {
+			account.hash = hash
+		}
+	}
+	return id, nil
+}
+
+// DeleteUser removes the account
+func (m *MemoryIdentity) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for email, account := range m.accounts {
+		if account.id == id {
+			delete(m.accounts, email)
+		}
+	}
+	return nil
+}
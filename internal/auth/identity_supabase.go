@@ -0,0 +1,109 @@
+// identity_supabase.go is the IdentityProvider backed by supabase auth through the gotrue-go client
+// gotrue-go reports failures as "response status code <n>: <body>", gotrueStatus reads the code back
+
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/supabase-community/gotrue-go"
+	"github.com/supabase-community/gotrue-go/types"
+)
+
+// SupabaseIdentity talks to supabase auth, admin is only used to delete users
+type SupabaseIdentity struct {
+	client gotrue.Client
+	admin  gotrue.Client
+}
+
+// make sure the implementation satisfies the interface at compile time
+var _ IdentityProvider = (*SupabaseIdentity)(nil)
+
+// NewSupabaseIdentity creates the provider, client uses the anon key and admin the service key
+func NewSupabaseIdentity(client, admin gotrue.Client) *SupabaseIdentity {
+	return &SupabaseIdentity{client: client, admin: admin}
+}
+
+// SignUp registers the email, supabase answers with a session when email confirmation is disabled
+func (s *SupabaseIdentity) SignUp(ctx context.Context, email, password string) (*SignUpResult, error) {
+	res, err := s.client.Signup(types.SignupRequest{Email: email, Password: password})
+	if err != nil {
+		if status := gotrueStatus(err); status == http.StatusBadRequest || status == http.StatusUnprocessableEntity {
+			message := strings.ToLower(err.Error())
+			if strings.Contains(message, "already") {
+				return nil, ErrEmailTaken
+			}
+			if strings.Contains(message, "password") {
+				return nil, fmt.Errorf("%w: %v", ErrPasswordRejected, err)
+			}
+		}
+		return nil, err
+	}
+
+	if res.Session.AccessToken != "" {
+		return &SignUpResult{UserID: res.Session.User.ID, Confirmed: true}, nil
+	}
+	// with confirmations on, supabase hides existing accounts behind a user without identities
+	if len(res.User.Identities) == 0 {
+		return nil, ErrEmailTaken
+	}
+	return &SignUpResult{UserID: res.User.ID}, nil
+}
+
+// SignIn uses the password grant
+func (s *SupabaseIdentity) SignIn(ctx context.Context, email, password string) (uuid.UUID, error) {
+	res, err := s.client.SignInWithEmailPassword(email, password)
+	if err != nil {
+		switch status := gotrueStatus(err); {
+		case status == http.StatusBadRequest && strings.Contains(strings.ToLower(err.Error()), "not confirmed"):
+			return uuid.Nil, ErrEmailNotConfirmed
+		case status == http.StatusBadRequest, status == http.StatusUnauthorized:
+			return uuid.Nil, ErrInvalidCredentials
+		}
+		return uuid.Nil, err
+	}
+	return res.User.ID, nil
+}
+
+// SignOut revokes the supabase refresh tokens of the session the access token belongs to
+func (s *SupabaseIdentity) SignOut(ctx context.Context, accessToken string) error {
+	return s.client.WithToken(accessToken).Logout()
+}
+
+// SendPasswordReset asks supabase to email the recovery link
+func (s *SupabaseIdentity) SendPasswordReset(ctx context.Context, email string) error {
+	return s.client.Recover(types.RecoverRequest{Email: email})
+}
+
+// ResetPassword updates the password as the user the recovery token belongs to
+func (s *SupabaseIdentity) ResetPassword(ctx context.Context, recoveryToken, password string) (uuid.UUID, error) {
+	res, err := s.client.WithToken(recoveryToken).UpdateUser(types.UpdateUserRequest{Password: &password})
+	if err != nil {
+		switch gotrueStatus(err) {
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return uuid.Nil, ErrInvalidToken
+		case http.StatusBadRequest, http.StatusUnprocessableEntity:
+			return uuid.Nil, fmt.Errorf("%w: %v", ErrPasswordRejected, err)
+		}
+		return uuid.Nil, err
+	}
+	return res.User.ID, nil
+}
+
+// DeleteUser removes the auth user with the service key
+func (s *SupabaseIdentity) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	return s.admin.AdminDeleteUser(types.AdminDeleteUserRequest{UserID: id})
+}
+
+// gotrueStatus extracts the http status gotrue-go puts at the start of its errors, 0 for other errors
+func gotrueStatus(err error) int {
+	var status int
+	if _, scanErr := fmt.Sscanf(err.Error(), "response status code %d", &status); scanErr != nil {
+		return 0
+	}
+	return status
+}
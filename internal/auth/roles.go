@@ -0,0 +1,31 @@
+// roles.go defines the roles a profile can have, they are stored in public.profiles.roles
+// and copied into the tokens issued by the api so most requests do not need to read them
+
+package auth
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// the roles known by the api, every profile has RoleUser
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// ValidRole reports whether role is one of the roles above
+func ValidRole(role string) bool {
+	switch role {
+	case RoleUser, RoleModerator, RoleAdmin:
+		return true
+	}
+	return false
+}
+
+// RoleLookup returns the stored roles of a user, repository.ProfileRepository implements it
+type RoleLookup interface {
+	Roles(ctx context.Context, userID uuid.UUID) ([]string, error)
+}
@@ -0,0 +1,298 @@
+// tokens.go is the TokenService, it mints access + refresh token pairs signed with JWT_SECRET (HS256)
+// refresh tokens belong to a session (a refresh token family stored by repository.SessionRepository),
+// each refresh rotates the token and replaying an old one revokes the whole session.
+// logged out access tokens go on a revocation list until they expire, AuthMiddleware checks it
+
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"feast-friends-api/internal/config"
+	"feast-friends-api/internal/repository"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// values of the token_type claim
+const (
+	AccessToken  = "access"
+	RefreshToken = "refresh"
+)
+
+// ErrInvalidToken is returned for tokens that are malformed, expired, badly signed or of the wrong type
+var ErrInvalidToken = errors.New("invalid token")
+
+// ErrTokenRevoked is returned for tokens on the revocation list or whose session was revoked
+var ErrTokenRevoked = errors.New("token revoked")
+
+// Claims are the claims of the tokens issued by the api
+type Claims struct {
+	TokenType string   `json:"token_type"`
+	Roles     []string `json:"roles,omitempty"`
+	SessionID string   `json:"sid"`
+	jwt.RegisteredClaims
+}
+
+// UserID returns the subject as a user id
+func (c *Claims) UserID() (uuid.UUID, error) {
+	return uuid.Parse(c.Subject)
+}
+
+// TokenPair is what clients receive after logging in or refreshing
+type TokenPair struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	TokenType    string    `json:"token_type"` // always Bearer
+	ExpiresIn    int       `json:"expires_in"` // seconds until the access token expires
+	ExpiresAt    time.Time `json:"expires_at"` // when the access token expires
+}
+
+// TokenService issues, refreshes, verifies and revokes session tokens
+type TokenService struct {
+	secret     []byte
+	issuer     string
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+	sessions   repository.SessionRepository
+	roles      RoleLookup
+}
+
+// NewTokenService creates the service, refreshTTL is the lifetime of a session
+func NewTokenService(secret []byte, issuer string, accessTTL, refreshTTL time.Duration, sessions repository.SessionRepository) *TokenService {
+	return &TokenService{secret: secret, issuer: issuer, accessTTL: accessTTL, refreshTTL: refreshTTL, sessions: sessions}
+}
+
+// NewTokenServiceFromConfig creates the service from the JWT config, failing on durations it cannot parse
+func NewTokenServiceFromConfig(cfg *config.Config, sessions repository.SessionRepository) (*TokenService, error) {
+	if cfg.JWT.Secret == "" {
+		return nil, errors.New("JWT_SECRET is required to issue session tokens")
+	}
+	accessTTL, err := ParseDuration(cfg.JWT.AccessExpiration)
+	if err != nil {
+		return nil, err
+	}
+	refreshTTL, err := ParseDuration(cfg.JWT.Expiration)
+	if err != nil {
+		return nil, err
+	}
+	return NewTokenService([]byte(cfg.JWT.Secret), cfg.JWT.SessionIssuer, accessTTL, refreshTTL, sessions), nil
+}
+
+// UseRoles makes Refresh re-read the roles of the user, so role changes reach the tokens
+// within one access token lifetime. without it the roles given to Issue are kept for the whole session
+func (s *TokenService) UseRoles(roles RoleLookup) {
+	s.roles = roles
+}
+
+// Roles returns the stored roles of the user, nil when no RoleLookup was set with UseRoles
+func (s *TokenService) Roles(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	if s.roles == nil {
+		return nil, nil
+	}
+	return s.roles.Roles(ctx, userID)
+}
+
+// Issue starts a new session for the user and returns its first token pair
+func (s *TokenService) Issue(ctx context.Context, userID uuid.UUID, roles []string) (*TokenPair, error) {
+	now := time.Now()
+	session := &repository.Session{
+		ID:         uuid.New(),
+		UserID:     userID,
+		CurrentJTI: uuid.New(),
+		ExpiresAt:  now.Add(s.refreshTTL),
+	}
+	if err := s.sessions.CreateSession(ctx, session); err != nil {
+		return nil, err
+	}
+	return s.pair(now, userID, roles, session.ID, session.CurrentJTI, session.ExpiresAt)
+}
+
+// Refresh rotates the refresh token and returns a new pair, the session keeps its original expiry
+// a refresh token that was already used returns repository.ErrTokenReused and revokes the session
+func (s *TokenService) Refresh(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	claims, err := s.parse(refreshToken, RefreshToken)
+	if err != nil {
+		return nil, err
+	}
+	userID, err := claims.UserID()
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	sessionID, err := uuid.Parse(claims.SessionID)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	jti, err := uuid.Parse(claims.ID)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	next := uuid.New()
+	err = s.sessions.RotateSession(ctx, sessionID, jti, next)
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return nil, ErrInvalidToken
+	case errors.Is(err, repository.ErrSessionRevoked):
+		return nil, ErrTokenRevoked
+	case err != nil:
+		return nil, err
+	}
+
+	roles := claims.Roles
+	if s.roles != nil {
+		roles, err = s.roles.Roles(ctx, userID)
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrInvalidToken
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s.pair(time.Now(), userID, roles, sessionID, next, claims.ExpiresAt.Time)
+}
+
+// VerifyAccess checks an access token issued by the api, including the revocation list
+func (s *TokenService) VerifyAccess(ctx context.Context, accessToken string) (*Claims, error) {
+	claims, err := s.parse(accessToken, AccessToken)
+	if err != nil {
+		return nil, err
+	}
+	revoked, err := s.sessions.IsTokenRevoked(ctx, claims.ID)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, ErrTokenRevoked
+	}
+	return claims, nil
+}
+
+// Revoke logs a token out: an access token goes on the revocation list and its session is revoked,
+// a refresh token revokes its session. tokens not issued by the api (supabase tokens) are put on
+// the revocation list by hash, the caller must have verified them already
+func (s *TokenService) Revoke(ctx context.Context, token string) error {
+	if !s.Issued(token) {
+		return s.sessions.RevokeToken(ctx, externalTokenID(token), s.unverifiedExpiry(token))
+	}
+
+	var claims Claims
+	if _, err := s.parser().ParseWithClaims(token, &claims, s.key); err != nil {
+		return ErrInvalidToken
+	}
+	if sessionID, err := uuid.Parse(claims.SessionID); err == nil {
+		if err := s.sessions.RevokeSession(ctx, sessionID); err != nil {
+			return err
+		}
+	}
+	if claims.TokenType == AccessToken {
+		return s.sessions.RevokeToken(ctx, claims.ID, claims.ExpiresAt.Time)
+	}
+	return nil
+}
+
+// RevokeUser ends every session of the user, access tokens already handed out live until they expire
+func (s *TokenService) RevokeUser(ctx context.Context, userID uuid.UUID) error {
+	return s.sessions.RevokeUserSessions(ctx, userID)
+}
+
+// IsRevoked reports whether a token that was not issued by the api was revoked with Revoke
+func (s *TokenService) IsRevoked(ctx context.Context, token string) (bool, error) {
+	return s.sessions.IsTokenRevoked(ctx, externalTokenID(token))
+}
+
+// Issued reports whether the token claims to come from this service, the signature is not checked
+func (s *TokenService) Issued(token string) bool {
+	var claims jwt.RegisteredClaims
+	if _, _, err := jwt.NewParser().ParseUnverified(token, &claims); err != nil {
+		return false
+	}
+	return claims.Issuer == s.issuer
+}
+
+// pair signs an access token and the refresh token jti of the session
+func (s *TokenService) pair(now time.Time, userID uuid.UUID, roles []string, sessionID, jti uuid.UUID, sessionExpiry time.Time) (*TokenPair, error) {
+	accessExpiry := now.Add(s.accessTTL)
+	if accessExpiry.After(sessionExpiry) {
+		accessExpiry = sessionExpiry
+	}
+
+	access, err := s.sign(AccessToken, userID, roles, sessionID, uuid.New(), now, accessExpiry)
+	if err != nil {
+		return nil, err
+	}
+	refresh, err := s.sign(RefreshToken, userID, roles, sessionID, jti, now, sessionExpiry)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(accessExpiry.Sub(now).Seconds()),
+		ExpiresAt:    accessExpiry.UTC(),
+	}, nil
+}
+
+// sign creates one HS256 token
+func (s *TokenService) sign(tokenType string, userID uuid.UUID, roles []string, sessionID, jti uuid.UUID, now, expiresAt time.Time) (string, error) {
+	claims := Claims{
+		TokenType: tokenType,
+		Roles:     roles,
+		SessionID: sessionID.String(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    s.issuer,
+			Subject:   userID.String(),
+			ID:        jti.String(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.secret)
+}
+
+// parse verifies the signature, expiry, issuer and type of a token issued by the service
+func (s *TokenService) parse(token, tokenType string) (*Claims, error) {
+	var claims Claims
+	if _, err := s.parser().ParseWithClaims(token, &claims, s.key); err != nil {
+		return nil, ErrInvalidToken
+	}
+	if claims.TokenType != tokenType {
+		return nil, ErrInvalidToken
+	}
+	return &claims, nil
+}
+
+// parser only accepts HS256 tokens from our issuer that have an expiry
+func (s *TokenService) parser() *jwt.Parser {
+	return jwt.NewParser(
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(s.issuer),
+		jwt.WithExpirationRequired(),
+	)
+}
+
+// key is the jwt.Keyfunc of the service
+func (s *TokenService) key(*jwt.Token) (interface{}, error) {
+	return s.secret, nil
+}
+
+// unverifiedExpiry reads exp without checking the signature, falling back to the access token lifetime
+func (s *TokenService) unverifiedExpiry(token string) time.Time {
+	var claims jwt.RegisteredClaims
+	if _, _, err := jwt.NewParser().ParseUnverified(token, &claims); err == nil && claims.ExpiresAt != nil {
+		return claims.ExpiresAt.Time
+	}
+	return time.Now().Add(s.accessTTL)
+}
+
+// externalTokenID is the revocation list key of a token without a jti we control
+func externalTokenID(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
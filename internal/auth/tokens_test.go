@@ -0,0 +1,200 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"feast-friends-api/internal/repository"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// roleMap is a RoleLookup backed by a map
+type roleMap map[uuid.UUID][]string
+
+func (m roleMap) Roles(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	roles, ok := m[userID]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return roles, nil
+}
+
+func newTestTokenService() *TokenService {
+	return NewTokenService([]byte("test-secret"), "feast-friends-test", 15*time.Minute, 7*24*time.Hour, repository.NewMemorySessionRepository())
+}
+
+func TestRefreshRotatesTheRefreshToken(t *testing.T) {
+	ctx := context.Background()
+	tokens := newTestTokenService()
+	userID := uuid.New()
+
+	first, err := tokens.Issue(ctx, userID, []string{"user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := tokens.Refresh(ctx, first.RefreshToken)
+	if err != nil {
+		t.Fatalf("first refresh: %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken {
+		t.Fatal("refresh returned the same refresh token")
+	}
+	third, err := tokens.Refresh(ctx, second.RefreshToken)
+	if err != nil {
+		t.Fatalf("refresh with the rotated token: %v", err)
+	}
+
+	claims, err := tokens.VerifyAccess(ctx, third.AccessToken)
+	if err != nil {
+		t.Fatalf("VerifyAccess: %v", err)
+	}
+	if claims.Subject != userID.String() || !slices.Equal(claims.Roles, []string{"user"}) {
+		t.Errorf("claims = %v %v, want the user and roles given to Issue", claims.Subject, claims.Roles)
+	}
+}
+
+func TestRefreshTokenReuseRevokesTheSession(t *testing.T) {
+	ctx := context.Background()
+	tokens := newTestTokenService()
+
+	first, err := tokens.Issue(ctx, uuid.New(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := tokens.Refresh(ctx, first.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := tokens.Refresh(ctx, first.RefreshToken); !errors.Is(err, repository.ErrTokenReused) {
+		t.Fatalf("replaying the old refresh token: error = %v, want ErrTokenReused", err)
+	}
+	if _, err := tokens.Refresh(ctx, second.RefreshToken); !errors.Is(err, ErrTokenRevoked) {
+		t.Fatalf("refresh after reuse: error = %v, want ErrTokenRevoked", err)
+	}
+}
+
+func TestRefreshRejectsInvalidTokens(t *testing.T) {
+	ctx := context.Background()
+	tokens := newTestTokenService()
+
+	pair, err := tokens.Issue(ctx, uuid.New(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := NewTokenService([]byte("other-secret"), "feast-friends-test", time.Minute, time.Hour, repository.NewMemorySessionRepository())
+	foreign, err := other.Issue(ctx, uuid.New(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		want  error
+	}{
+		{"garbage", "not.a.token", ErrInvalidToken},
+		{"access token", pair.AccessToken, ErrInvalidToken},
+		{"other secret", foreign.RefreshToken, ErrInvalidToken},
+		{"unknown session", func() string {
+			token, err := tokens.sign(RefreshToken, uuid.New(), nil, uuid.New(), uuid.New(), time.Now(), time.Now().Add(time.Hour))
+			if err != nil {
+				t.Fatal(err)
+			}
+			return token
+		}(), ErrInvalidToken},
+		{"expired", func() string {
+			token, err := tokens.sign(RefreshToken, uuid.New(), nil, uuid.New(), uuid.New(), time.Now().Add(-2*time.Hour), time.Now().Add(-time.Hour))
+			if err != nil {
+				t.Fatal(err)
+			}
+			return token
+		}(), ErrInvalidToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tokens.Refresh(ctx, tt.token); !errors.Is(err, tt.want) {
+				t.Errorf("Refresh error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRevokeEndsTheSession(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+
+	tests := []struct {
+		name   string
+		revoke func(tokens *TokenService, pair *TokenPair) error
+		access error
+	}{
+		{
+			name:   "access token",
+			revoke: func(tokens *TokenService, pair *TokenPair) error { return tokens.Revoke(ctx, pair.AccessToken) },
+			access: ErrTokenRevoked,
+		},
+		{
+			name:   "refresh token",
+			revoke: func(tokens *TokenService, pair *TokenPair) error { return tokens.Revoke(ctx, pair.RefreshToken) },
+		},
+		{
+			name:   "user",
+			revoke: func(tokens *TokenService, pair *TokenPair) error { return tokens.RevokeUser(ctx, userID) },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens := newTestTokenService()
+			pair, err := tokens.Issue(ctx, userID, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := tt.revoke(tokens, pair); err != nil {
+				t.Fatalf("revoke: %v", err)
+			}
+			if _, err := tokens.Refresh(ctx, pair.RefreshToken); !errors.Is(err, ErrTokenRevoked) {
+				t.Errorf("Refresh error = %v, want ErrTokenRevoked", err)
+			}
+			// access tokens that were not revoked themselves live until they expire
+			if _, err := tokens.VerifyAccess(ctx, pair.AccessToken); !errors.Is(err, tt.access) {
+				t.Errorf("VerifyAccess error = %v, want %v", err, tt.access)
+			}
+		})
+	}
+}
+
+func TestRefreshRereadsRoles(t *testing.T) {
+	ctx := context.Background()
+	tokens := newTestTokenService()
+	userID := uuid.New()
+	roles := roleMap{userID: {"user", "admin"}}
+	tokens.UseRoles(roles)
+
+	pair, err := tokens.Issue(ctx, userID, roles[userID])
+	if err != nil {
+		t.Fatal(err)
+	}
+	roles[userID] = []string{"user"}
+
+	refreshed, err := tokens.Refresh(ctx, pair.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := tokens.VerifyAccess(ctx, refreshed.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(claims.Roles, []string{"user"}) {
+		t.Errorf("roles = %v, want the stored roles [user]", claims.Roles)
+	}
+
+	delete(roles, userID)
+	if _, err := tokens.Refresh(ctx, refreshed.RefreshToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("refresh of a deleted user: error = %v, want ErrInvalidToken", err)
+	}
+}
@@ -7,6 +7,9 @@ package config
 
 import (
 	"log"
+	"os"
+	"strings"
+	"time"
 	"github.com/joho/godotenv"
 	"github.com/kelseyhightower/envconfig"
 
@@ -18,24 +21,108 @@ type Config struct {
 	Server struct {
 		Port string `envconfig:"SERVER_PORT" default:"8000"`
 		GinMode string `envconfig:"GIN_MODE" default:"debug"`
-		Frontend string `envconfig:"FRONTEND_RUL" default:"https://localhost:3000"` 
-
+		// FRONTEND_RUL (the old misspelled name) is still read when FRONTEND_URL is not set
+		Frontend string `envconfig:"FRONTEND_URL" default:"https://localhost:3000"` 
+		// timeouts use go duration strings e.g "15s", "1m"
+		ReadTimeout     time.Duration `envconfig:"SERVER_READ_TIMEOUT" default:"15s"`
+		WriteTimeout    time.Duration `envconfig:"SERVER_WRITE_TIMEOUT" default:"15s"`
+		IdleTimeout     time.Duration `envconfig:"SERVER_IDLE_TIMEOUT" default:"60s"`
+		ShutdownTimeout time.Duration `envconfig:"SERVER_SHUTDOWN_TIMEOUT" default:"30s"`
+		// how long the server keeps serving with a failing readiness probe before it stops accepting
+		// connections, it gives load balancers time to notice, 0 shuts down right away
+		DrainDelay time.Duration `envconfig:"SERVER_DRAIN_DELAY" default:"5s"`
+		// ips or cidrs of the load balancers in front of the api, X-Forwarded-For and X-Real-IP
+		// are only believed when the request comes from one of them
+		TrustedProxies []string `envconfig:"TRUSTED_PROXIES"`
+	}
+	Database struct {
+		// default timeout of each query run through the utils db helpers, 0 disables it
+		QueryTimeout time.Duration `envconfig:"DB_QUERY_TIMEOUT" default:"5s"`
+		// how many times utils.WithTx runs a transaction that hit a serialization failure or deadlock
+		TxMaxAttempts int `envconfig:"DB_TX_MAX_ATTEMPTS" default:"3"`
 	}
 	Supabase struct{
 		URL string `envconfig:"SUPABASE_URL" required:"true"`
 		AKey string `envconfig:"SUPABASE_ANON_KEY" required:"true"`
 		Skey string `envconfig:"SUPABASE_SERVICE_KEY" required:"true"`
+		// gotrue endpoint, derived from SUPABASE_URL when empty
+		AuthURL string `envconfig:"SUPABASE_AUTH_URL"`
 	}
 	JWT struct {
 		Secret     string `envconfig:"JWT_SECRET" required:"true"`
+		// lifetime of the sessions issued by the api (refresh tokens), e.g "7d" or "24H"
 		Expiration string `envconfig:"JWT_EXPIRATION" default:"7d"`
+		// lifetime of the access tokens issued by the api
+		AccessExpiration string `envconfig:"JWT_ACCESS_EXPIRATION" default:"15m"`
+		// iss claim of the tokens issued by the api, it tells them apart from supabase tokens
+		SessionIssuer string `envconfig:"JWT_SESSION_ISSUER" default:"feast-friends-api"`
+		// local verifies tokens in process (HS256 with the secret, RS256/ES256 with the JWKS),
+		// remote asks supabase auth about every token
+		Mode     string `envconfig:"JWT_MODE" default:"local"`
+		Audience string `envconfig:"JWT_AUDIENCE" default:"authenticated"`
+		// issuer and jwks url are derived from SUPABASE_URL when empty
+		Issuer      string        `envconfig:"JWT_ISSUER"`
+		JWKSURL     string        `envconfig:"JWT_JWKS_URL"`
+		JWKSRefresh time.Duration `envconfig:"JWT_JWKS_REFRESH" default:"10m"`
+		// clock skew allowed when checking exp and nbf
+		Leeway time.Duration `envconfig:"JWT_LEEWAY" default:"30s"`
 	}
 	FileUpload struct {
 		// Stored in bytes. 10485760 bytes = 10 MB
 		MaxFileSize int64 `envconfig:"MAX_FILE_SIZE" default:"10485760"`
 	}
+	CORS struct {
+		// comma separated, "https://*.example.com" allows every subdomain and "*" any origin (only without credentials)
+		// defaults to FRONTEND_URL when empty
+		AllowedOrigins   []string      `envconfig:"CORS_ALLOWED_ORIGINS"`
+		AllowedMethods   []string      `envconfig:"CORS_ALLOWED_METHODS" default:"GET,POST,PUT,PATCH,DELETE,OPTIONS"`
+		AllowedHeaders   []string      `envconfig:"CORS_ALLOWED_HEADERS" default:"Content-Type,Authorization,X-Request-ID"`
+		ExposedHeaders   []string      `envconfig:"CORS_EXPOSED_HEADERS" default:"X-Request-ID,RateLimit-Limit,RateLimit-Remaining,RateLimit-Reset,Retry-After"`
+		AllowCredentials bool          `envconfig:"CORS_ALLOW_CREDENTIALS" default:"true"`
+		MaxAge           time.Duration `envconfig:"CORS_MAX_AGE" default:"10m"`
+	}
+	Realtime struct {
+		// local keeps realtime events in process, postgres fans them out with LISTEN/NOTIFY for multiple replicas
+		Hub     string `envconfig:"REALTIME_HUB" default:"local"`
+		Channel string `envconfig:"REALTIME_CHANNEL" default:"realtime_events"`
+	}
+	RateLimit struct {
+		// limits are "<requests>/<period>" token buckets, e.g "10/m" or "5/30s", "off" disables one
+		Enabled bool `envconfig:"RATE_LIMIT_ENABLED" default:"true"`
+		// every request, per client ip
+		Global RateLimit `envconfig:"RATE_LIMIT_GLOBAL" default:"300/m"`
+		// sign up, login, refresh and password reset, per client ip
+		Auth RateLimit `envconfig:"RATE_LIMIT_AUTH" default:"10/m"`
+		// creating posts, comments and events, per user
+		Posts RateLimit `envconfig:"RATE_LIMIT_POSTS" default:"20/m"`
+		// opening conversations, sending messages and typing, per user
+		Messages RateLimit `envconfig:"RATE_LIMIT_MESSAGES" default:"60/m"`
+	}
+	Health struct {
+		// how long /health/ready waits for each dependency
+		Timeout time.Duration `envconfig:"HEALTH_CHECK_TIMEOUT" default:"2s"`
+		// how long a readiness report is reused before the dependencies are checked again
+		CacheTTL time.Duration `envconfig:"HEALTH_CHECK_CACHE_TTL" default:"5s"`
+	}
+	Metrics struct {
+		// serves the prometheus metrics on GET /metrics, the server refuses to start without a token
+		Enabled bool `envconfig:"METRICS_ENABLED" default:"false"`
+		// scrapers must send it as a bearer token
+		Token string `envconfig:"METRICS_TOKEN"`
+	}
+	Tracing struct {
+		// none disables tracing, stdout prints the spans (handy in dev), otlp sends them to a collector over http
+		Exporter string `envconfig:"TRACING_EXPORTER" default:"none"`
+		// full url of the OTLP http traces receiver, https urls use tls
+		Endpoint string `envconfig:"TRACING_OTLP_ENDPOINT" default:"http://localhost:4318/v1/traces"`
+		// share of new traces that are recorded, requests with a sampled traceparent are always recorded
+		SampleRatio float64 `envconfig:"TRACING_SAMPLE_RATIO" default:"1"`
+		ServiceName string  `envconfig:"TRACING_SERVICE_NAME" default:"feast-friends-api"`
+	}
 	Logging struct {
 		Level string `envconfig:"LOG_LEVEL" default:"debug"`
+		// recovered panics are appended to this file as json lines, empty only logs them
+		CrashFile string `envconfig:"CRASH_REPORT_FILE"`
 	}
 }
 
@@ -59,8 +146,31 @@ func init(){
 		log.Printf("Failed to Load Config:%v", err)
 	}
 
+	//FRONTEND_URL used to be spelled FRONTEND_RUL, keep old .env files working
+	if _, ok := os.LookupEnv("FRONTEND_URL"); !ok {
+		if legacy, ok := os.LookupEnv("FRONTEND_RUL"); ok {
+			log.Println("FRONTEND_RUL is deprecated, rename it to FRONTEND_URL")
+			cfg.Server.Frontend = legacy
+		}
+	}
+	if len(cfg.CORS.AllowedOrigins) == 0 {
+		cfg.CORS.AllowedOrigins = []string{cfg.Server.Frontend}
+	}
+
 	if cfg.JWT.Expiration == "" {
 		cfg.JWT.Expiration = "24H"
 	} 
 
+	//supabase signs its tokens as <project url>/auth/v1 and serves the keys under the same path
+	if cfg.Supabase.AuthURL == "" && cfg.Supabase.URL != "" {
+		cfg.Supabase.AuthURL = strings.TrimRight(cfg.Supabase.URL, "/") + "/auth/v1"
+	}
+	if cfg.JWT.Issuer == "" {
+		cfg.JWT.Issuer = cfg.Supabase.AuthURL
+	}
+	if cfg.JWT.JWKSURL == "" && cfg.Supabase.AuthURL != "" {
+		cfg.JWT.JWKSURL = cfg.Supabase.AuthURL + "/.well-known/jwks.json"
+	}
+	cfg.JWT.Mode = strings.ToLower(strings.TrimSpace(cfg.JWT.Mode))
+
 }
\ No newline at end of file
@@ -0,0 +1,62 @@
+// ratelimit.go contains the RateLimit type used by the RATE_LIMIT_* variables
+// envconfig calls Decode so a malformed limit is reported when the config is loaded
+
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RateLimit is a token bucket: it holds Requests tokens and refills them evenly over Period
+// the zero value means no limit
+type RateLimit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// periodUnits are the single letter periods accepted on top of go durations ("10/m" is "10/1m")
+var periodUnits = map[string]time.Duration{"s": time.Second, "m": time.Minute, "h": time.Hour, "d": 24 * time.Hour}
+
+// Decode parses "<requests>/<period>" e.g "10/m", "100/h" or "5/30s", "off" and "0" disable the limit
+func (l *RateLimit) Decode(value string) error {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "off" || value == "0" || value == "" {
+		*l = RateLimit{}
+		return nil
+	}
+
+	requests, period, ok := strings.Cut(value, "/")
+	if !ok {
+		return fmt.Errorf("invalid rate limit %q, expected <requests>/<period> e.g 10/m", value)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(requests))
+	if err != nil || n < 0 {
+		return fmt.Errorf("invalid rate limit %q, requests must be a positive number", value)
+	}
+	period = strings.TrimSpace(period)
+	d, ok := periodUnits[period]
+	if !ok {
+		if d, err = time.ParseDuration(period); err != nil || d <= 0 {
+			return fmt.Errorf("invalid rate limit %q, period must be s, m, h, d or a duration", value)
+		}
+	}
+
+	*l = RateLimit{Requests: n, Period: d}
+	return nil
+}
+
+// Enabled reports whether the limit allows a finite number of requests
+func (l RateLimit) Enabled() bool {
+	return l.Requests > 0 && l.Period > 0
+}
+
+// String formats the limit the way Decode reads it
+func (l RateLimit) String() string {
+	if !l.Enabled() {
+		return "off"
+	}
+	return fmt.Sprintf("%d/%s", l.Requests, l.Period)
+}
@@ -0,0 +1,341 @@
+// auth.go contains the account and session endpoints
+// the IdentityProvider (supabase auth) owns emails and passwords, the token service in internal/auth
+// hands out the session tokens. access tokens are short lived, clients trade their refresh token
+// for a new pair before it expires
+
+package handlers
+
+import (
+	"context"
+	"errors"
+	"feast-friends-api/internal/auth"
+	"feast-friends-api/internal/models"
+	"feast-friends-api/internal/repository"
+	"feast-friends-api/internal/utils"
+	"feast-friends-api/pkg/logger"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// AuthHandler serves the /auth endpoints
+type AuthHandler struct {
+	tokens   *auth.TokenService
+	identity auth.IdentityProvider
+	profiles repository.ProfileRepository
+}
+
+// NewAuthHandler creates the handler using the given token service, identity provider and profiles
+func NewAuthHandler(tokens *auth.TokenService, identity auth.IdentityProvider, profiles repository.ProfileRepository) *AuthHandler {
+	return &AuthHandler{tokens: tokens, identity: identity, profiles: profiles}
+}
+
+// signUpRequest is the body accepted when creating an account
+type signUpRequest struct {
+	Email    string `json:"email"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	FullName string `json:"full_name"`
+}
+
+// loginRequest is the body accepted when logging in
+type loginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// forgotPasswordRequest is the body accepted when asking for a recovery email
+type forgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+// resetPasswordRequest is the body accepted when setting a new password,
+// token is the access token of the recovery link
+type resetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// refreshRequest is the body accepted when refreshing a session
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// sessionResponse is returned once the user is logged in
+type sessionResponse struct {
+	User    *models.User    `json:"user"`
+	Session *auth.TokenPair `json:"session,omitempty"`
+}
+
+// SignUp handles POST /auth/signup, it creates the account and the public.profiles row
+// when the provider requires email confirmation no session is returned
+func (h *AuthHandler) SignUp(w http.ResponseWriter, r *http.Request) {
+	var req signUpRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	req.Email = strings.ToLower(strings.TrimSpace(req.Email))
+	req.Username = strings.TrimSpace(req.Username)
+	req.FullName = strings.TrimSpace(req.FullName)
+
+	fields := map[string]string{}
+	if !utils.EmailIsValid(req.Email) {
+		fields["email"] = "must be a valid email address"
+	}
+	if !utils.UsernameIsValid(req.Username) {
+		fields["username"] = "must be 3 to 20 letters, digits, _ . or !"
+	}
+	if len(req.FullName) > 50 {
+		fields["full_name"] = "must be at most 50 characters"
+	}
+	violations := utils.PasswordRuleViolations(req.Password)
+	if len(violations) > 0 {
+		fields["password"] = passwordMessage(violations)
+	}
+	if len(fields) > 0 {
+		writePasswordErrors(w, fields, violations)
+		return
+	}
+
+	taken, err := h.profiles.UsernameTaken(r.Context(), req.Username)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to create account", err)
+		return
+	}
+	if taken {
+		writeError(w, http.StatusConflict, "Username already taken", repository.ErrUsernameTaken)
+		return
+	}
+
+	account, err := h.identity.SignUp(r.Context(), req.Email, req.Password)
+	switch {
+	case errors.Is(err, auth.ErrEmailTaken):
+		writeError(w, http.StatusConflict, "Email already registered", err)
+		return
+	case errors.Is(err, auth.ErrPasswordRejected):
+		writePasswordErrors(w, map[string]string{"password": "was rejected, choose a different password"}, nil)
+		return
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, "Failed to create account", err)
+		return
+	}
+
+	user := &models.User{ID: account.UserID, Email: req.Email, Username: req.Username, FullName: req.FullName}
+	if err := h.profiles.Create(r.Context(), user); err != nil {
+		// without a profile the account is unusable, remove it so the email can sign up again
+		if deleteErr := h.identity.DeleteUser(context.WithoutCancel(r.Context()), account.UserID); deleteErr != nil {
+			logger.ErrorContext(r.Context(), "failed to roll back sign up of user %v: %v", account.UserID, deleteErr)
+		}
+		if errors.Is(err, repository.ErrUsernameTaken) {
+			writeError(w, http.StatusConflict, "Username already taken", err)
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "Failed to create account", err)
+		return
+	}
+
+	if !account.Confirmed {
+		utils.WriteJSON(w, http.StatusCreated, utils.SuccessResponse(sessionResponse{User: user},
+			"account created, check your email to confirm it"))
+		return
+	}
+
+	// new profiles only have the default role
+	pair, err := h.tokens.Issue(r.Context(), user.ID, []string{auth.RoleUser})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Account created but failed to log in", err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusCreated, utils.SuccessResponse(sessionResponse{User: user, Session: pair}, "account created"))
+}
+
+// Login handles POST /auth/login with {"email": "...", "password": "..."} and starts a session
+func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
+	var req loginRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	req.Email = strings.ToLower(strings.TrimSpace(req.Email))
+
+	fields := map[string]string{}
+	if !utils.EmailIsValid(req.Email) {
+		fields["email"] = "must be a valid email address"
+	}
+	if req.Password == "" {
+		fields["password"] = "is required"
+	}
+	if len(fields) > 0 {
+		utils.WriteJSON(w, http.StatusUnprocessableEntity, utils.FieldErrorsResponse("Validation failed", fields))
+		return
+	}
+
+	userID, err := h.identity.SignIn(r.Context(), req.Email, req.Password)
+	switch {
+	case errors.Is(err, auth.ErrInvalidCredentials):
+		writeError(w, http.StatusUnauthorized, "Invalid email or password", err)
+		return
+	case errors.Is(err, auth.ErrEmailNotConfirmed):
+		writeError(w, http.StatusForbidden, "Confirm your email before logging in", err)
+		return
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, "Failed to log in", err)
+		return
+	}
+
+	user, err := h.profiles.GetByID(r.Context(), userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		writeError(w, http.StatusForbidden, "Account has no profile", err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to log in", err)
+		return
+	}
+
+	roles, err := h.profiles.Roles(r.Context(), userID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to log in", err)
+		return
+	}
+
+	pair, err := h.tokens.Issue(r.Context(), userID, roles)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to log in", err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.SuccessResponse(sessionResponse{User: user, Session: pair}, "logged in"))
+}
+
+// Logout handles POST /auth/logout, the access token of the request stops working right away
+// tokens issued by supabase also end their supabase session
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimSpace(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+
+	if err := h.tokens.Revoke(r.Context(), token); err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to log out", err)
+		return
+	}
+	if !h.tokens.Issued(token) {
+		if err := h.identity.SignOut(r.Context(), token); err != nil {
+			// the token is already on the revocation list, the supabase session expires by itself
+			logger.WarnContext(r.Context(), "failed to end supabase session: %v", err)
+		}
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.SuccessResponse(nil, "logged out"))
+}
+
+// ForgotPassword handles POST /auth/password/forgot with {"email": "..."}
+// the answer is the same whether the email has an account or not
+func (h *AuthHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req forgotPasswordRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	req.Email = strings.ToLower(strings.TrimSpace(req.Email))
+	if !utils.EmailIsValid(req.Email) {
+		utils.WriteJSON(w, http.StatusUnprocessableEntity, utils.FieldErrorsResponse("Validation failed",
+			map[string]string{"email": "must be a valid email address"}))
+		return
+	}
+
+	if err := h.identity.SendPasswordReset(r.Context(), req.Email); err != nil {
+		// not reported to the client so the endpoint cannot be used to find accounts
+		logger.ErrorContext(r.Context(), "failed to send password reset: %v", err)
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.SuccessResponse(nil, "if the email has an account a reset link was sent"))
+}
+
+// ResetPassword handles POST /auth/password/reset with {"token": "...", "password": "..."}
+// every session of the user is ended so a stolen session does not survive the reset
+func (h *AuthHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req resetPasswordRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	fields := map[string]string{}
+	if strings.TrimSpace(req.Token) == "" {
+		fields["token"] = "is required"
+	}
+	violations := utils.PasswordRuleViolations(req.Password)
+	if len(violations) > 0 {
+		fields["password"] = passwordMessage(violations)
+	}
+	if len(fields) > 0 {
+		writePasswordErrors(w, fields, violations)
+		return
+	}
+
+	userID, err := h.identity.ResetPassword(r.Context(), req.Token, req.Password)
+	switch {
+	case errors.Is(err, auth.ErrInvalidToken):
+		writeError(w, http.StatusUnauthorized, "Invalid or expired reset link", err)
+		return
+	case errors.Is(err, auth.ErrPasswordRejected):
+		writePasswordErrors(w, map[string]string{"password": "was rejected, choose a different password"}, nil)
+		return
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, "Failed to reset password", err)
+		return
+	}
+
+	if err := h.tokens.RevokeUser(r.Context(), userID); err != nil {
+		logger.ErrorContext(r.Context(), "failed to end sessions of user %v after password reset: %v", userID, err)
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.SuccessResponse(map[string]uuid.UUID{"user_id": userID}, "password updated, please log in again"))
+}
+
+// Refresh handles POST /auth/refresh with {"refresh_token": "..."} and returns a new token pair
+// the refresh token can only be used once, replaying it ends the session
+func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req refreshRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if req.RefreshToken == "" {
+		utils.WriteJSON(w, http.StatusUnprocessableEntity, utils.FieldErrorsResponse("Validation failed",
+			map[string]string{"refresh_token": "is required"}))
+		return
+	}
+
+	pair, err := h.tokens.Refresh(r.Context(), req.RefreshToken)
+	switch {
+	case errors.Is(err, repository.ErrTokenReused):
+		writeError(w, http.StatusUnauthorized, "Refresh token was already used, please log in again", err)
+		return
+	case errors.Is(err, auth.ErrInvalidToken), errors.Is(err, auth.ErrTokenRevoked):
+		writeError(w, http.StatusUnauthorized, "Invalid or expired refresh token", err)
+		return
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, "Failed to refresh session", err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.SuccessResponse(pair, "session refreshed"))
+}
+
+// passwordMessage joins the messages of the broken rules, e.g. "must contain a digit; must contain an uppercase letter"
+func passwordMessage(violations []utils.PasswordRule) string {
+	messages := make([]string, len(violations))
+	for i, v := range violations {
+		messages[i] = v.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// writePasswordErrors writes a 422 with the field errors and, when given, the list of broken password rules
+// so clients can tick off the requirements next to the password input
+func writePasswordErrors(w http.ResponseWriter, fields map[string]string, violations []utils.PasswordRule) {
+	body := utils.FieldErrorsResponse("Validation failed", fields)
+	if len(violations) > 0 {
+		body["password_rules"] = violations
+	}
+	utils.WriteJSON(w, http.StatusUnprocessableEntity, body)
+}
@@ -0,0 +1,290 @@
+// comments.go contains the threaded comment handlers
+// threads are returned as trees limited to ?depth= levels of replies, clients load deeper
+// replies with /comments/{id}/replies when reply_count is bigger than the replies returned
+
+package handlers
+
+import (
+	"errors"
+	"feast-friends-api/internal/models"
+	"feast-friends-api/internal/repository"
+	"feast-friends-api/internal/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultCommentDepth = 3
+	maxCommentDepth     = 10
+)
+
+var (
+	// errNotCommentOwner is returned when a user tries to change someone elses comment
+	errNotCommentOwner = errors.New("user is not the author of the comment")
+	// errCommentDeleted is returned when editing a comment that was already deleted
+	errCommentDeleted = errors.New("comment was deleted")
+)
+
+// CommentHandler serves the comment endpoints
+type CommentHandler struct {
+	comments repository.CommentRepository
+}
+
+// NewCommentHandler creates the handler using the given comment repository
+func NewCommentHandler(comments repository.CommentRepository) *CommentHandler {
+	return &CommentHandler{comments: comments}
+}
+
+// commentRequest is the body accepted when creating or editing a comment
+type commentRequest struct {
+	Content  string     `json:"content"`
+	ParentID *uuid.UUID `json:"parent_id"`
+}
+
+// Create handles POST /posts/{id}/comments, set parent_id to reply to another comment
+func (h *CommentHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID, err := currentUserID(r)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "Authentication required", err)
+		return
+	}
+	postID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid post id", err)
+		return
+	}
+
+	var req commentRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	comment := &models.Comment{UserID: userID, PostID: postID, ParentID: req.ParentID, Content: req.Content}
+	if err := comment.Validate(); err != nil {
+		utils.WriteJSON(w, http.StatusUnprocessableEntity, utils.ValidationErrorResponse(err))
+		return
+	}
+
+	if err := h.comments.Create(r.Context(), comment); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "Post or parent comment not found", err)
+			return
+		}
+		if errors.Is(err, repository.ErrParentDeleted) {
+			writeError(w, http.StatusConflict, "Deleted comments cannot be replied to", err)
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "Failed to create comment", err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.SuccessResponse(comment, "comment created"))
+}
+
+// ListByPost handles GET /posts/{id}/comments?page=&limit=&depth=
+// pagination applies to top level comments, each one comes with its reply tree
+func (h *CommentHandler) ListByPost(w http.ResponseWriter, r *http.Request) {
+	postID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid post id", err)
+		return
+	}
+	page, limit, offset := pagination(r)
+	depth := commentDepth(r)
+
+	flat, total, err := h.comments.ListThread(r.Context(), postID, limit, offset, depth)
+	if errors.Is(err, repository.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "Post not found", err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to load comments", err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.PaginatedResponse("comments found", buildCommentTree(flat), total, page, limit))
+}
+
+// ListReplies handles GET /comments/{id}/replies?depth=
+func (h *CommentHandler) ListReplies(w http.ResponseWriter, r *http.Request) {
+	commentID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid comment id", err)
+		return
+	}
+
+	if _, err := h.comments.GetByID(r.Context(), commentID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "Comment not found", err)
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "Failed to load comment", err)
+		return
+	}
+
+	flat, err := h.comments.ListReplies(r.Context(), commentID, commentDepth(r))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to load replies", err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.SuccessResponse(buildCommentTree(flat), "replies found"))
+}
+
+// ListEdits handles GET /comments/{id}/edits, the earlier versions of the comment oldest first
+func (h *CommentHandler) ListEdits(w http.ResponseWriter, r *http.Request) {
+	commentID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid comment id", err)
+		return
+	}
+
+	edits, err := h.comments.ListEdits(r.Context(), commentID)
+	if errors.Is(err, repository.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "Comment not found", err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to load comment edits", err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.SuccessResponse(edits, "edits found"))
+}
+
+// Update handles PUT /comments/{id}, only the author can edit and deleted comments cannot be edited
+func (h *CommentHandler) Update(w http.ResponseWriter, r *http.Request) {
+	comment, ok := h.ownedComment(w, r)
+	if !ok {
+		return
+	}
+	if comment.Deleted {
+		writeError(w, http.StatusConflict, "Deleted comments cannot be edited", errCommentDeleted)
+		return
+	}
+
+	var req commentRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	comment.Content = req.Content
+	if err := comment.Validate(); err != nil {
+		utils.WriteJSON(w, http.StatusUnprocessableEntity, utils.ValidationErrorResponse(err))
+		return
+	}
+
+	if err := h.comments.UpdateContent(r.Context(), comment); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "Comment not found", err)
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "Failed to update comment", err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.SuccessResponse(comment, "comment updated"))
+}
+
+// Delete handles DELETE /comments/{id}, the comment is soft deleted so its replies stay visible
+func (h *CommentHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	comment, ok := h.ownedComment(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.comments.SoftDelete(r.Context(), comment.ID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "Comment not found", err)
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "Failed to delete comment", err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.SuccessResponse(nil, "comment deleted"))
+}
+
+// ownedComment loads the comment from the {id} path param and checks the current user wrote it
+// it writes the error response itself and returns false when the request should stop
+func (h *CommentHandler) ownedComment(w http.ResponseWriter, r *http.Request) (*models.Comment, bool) {
+	userID, err := currentUserID(r)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "Authentication required", err)
+		return nil, false
+	}
+	id, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid comment id", err)
+		return nil, false
+	}
+
+	comment, err := h.comments.GetByID(r.Context(), id)
+	if errors.Is(err, repository.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "Comment not found", err)
+		return nil, false
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to load comment", err)
+		return nil, false
+	}
+
+	if comment.UserID != userID {
+		writeError(w, http.StatusForbidden, "Only the author can modify this comment", errNotCommentOwner)
+		return nil, false
+	}
+	return comment, true
+}
+
+// commentDepth reads ?depth=, the number of reply levels to return (default 3, between 1 and 10)
+func commentDepth(r *http.Request) int {
+	depth, err := strconv.Atoi(r.URL.Query().Get("depth"))
+	if err != nil || depth < 1 {
+		return defaultCommentDepth
+	}
+	if depth > maxCommentDepth {
+		return maxCommentDepth
+	}
+	return depth
+}
+
+// buildCommentTree nests the flat (oldest first) comments under their parents
+// comments whose parent is not in the list are the roots of the returned trees
+func buildCommentTree(flat []models.CommentWithUser) []models.CommentWithUser {
+	index := make(map[uuid.UUID]int, len(flat))
+	for i, c := range flat {
+		index[c.ID] = i
+	}
+
+	children := make(map[uuid.UUID][]int)
+	roots := []int{}
+	for i, c := range flat {
+		if c.ParentID != nil {
+			if _, ok := index[*c.ParentID]; ok {
+				children[*c.ParentID] = append(children[*c.ParentID], i)
+				continue
+			}
+		}
+		roots = append(roots, i)
+	}
+
+	var build func(i int) models.CommentWithUser
+	build = func(i int) models.CommentWithUser {
+		node := flat[i]
+		node.Replies = []models.CommentWithUser{}
+		for _, child := range children[node.ID] {
+			node.Replies = append(node.Replies, build(child))
+		}
+		return node
+	}
+
+	tree := make([]models.CommentWithUser, 0, len(roots))
+	for _, i := range roots {
+		tree = append(tree, build(i))
+	}
+	return tree
+}
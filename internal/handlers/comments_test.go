@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"feast-friends-api/internal/models"
+	"feast-friends-api/internal/repository"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// comment returns a comment with a readable id, parent "" makes it top level
+func comment(id, parent string) models.CommentWithUser {
+	c := models.CommentWithUser{Comment: models.Comment{ID: commentID(id), Content: id}}
+	if parent != "" {
+		parentID := commentID(parent)
+		c.ParentID = &parentID
+	}
+	return c
+}
+
+// commentID derives a stable uuid from a short name
+func commentID(name string) uuid.UUID {
+	return uuid.NewSHA1(uuid.NameSpaceOID, []byte(name))
+}
+
+// shape prints the tree as "a(b(c) d)" so cases are easy to compare
+func shape(tree []models.CommentWithUser) string {
+	parts := make([]string, len(tree))
+	for i, c := range tree {
+		parts[i] = c.Content
+		if len(c.Replies) > 0 {
+			parts[i] += "(" + shape(c.Replies) + ")"
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestBuildCommentTree(t *testing.T) {
+	tests := []struct {
+		name string
+		flat []models.CommentWithUser
+		want string
+	}{
+		{"empty", nil, ""},
+		{"top level only", []models.CommentWithUser{comment("a", ""), comment("b", "")}, "a b"},
+		{
+			"nested replies keep their order",
+			[]models.CommentWithUser{comment("a", ""), comment("b", "a"), comment("c", "b"), comment("d", "a"), comment("e", "")},
+			"a(b(c) d) e",
+		},
+		{
+			"missing parent becomes a root",
+			[]models.CommentWithUser{comment("b", "a"), comment("c", "b"), comment("d", "x")},
+			"b(c) d",
+		},
+		{
+			"replies listed before a later root",
+			[]models.CommentWithUser{comment("a", ""), comment("b", ""), comment("c", "b"), comment("d", "a")},
+			"a(d) b(c)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree := buildCommentTree(tt.flat)
+			if tree == nil {
+				t.Fatal("buildCommentTree returned nil, want an empty list so it encodes as []")
+			}
+			if got := shape(tree); got != tt.want {
+				t.Errorf("buildCommentTree() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildCommentTreeRepliesAreNeverNil(t *testing.T) {
+	tree := buildCommentTree([]models.CommentWithUser{comment("a", "")})
+	if tree[0].Replies == nil {
+		t.Error("leaf comment has nil replies, want an empty list so it encodes as []")
+	}
+}
+
+func TestCommentHandlerStatusCodes(t *testing.T) {
+	author := uuid.New()
+	stranger := uuid.New()
+
+	tests := []struct {
+		name    string
+		pattern string
+		method  string
+		target  string // {post}, {comment} and {deleted} are replaced with the ids of a stored post, comment and deleted comment
+		userID  uuid.UUID
+		body    string
+		want    int
+	}{
+		{"list", "GET /posts/{id}/comments", http.MethodGet, "/posts/{post}/comments", uuid.Nil, "", http.StatusOK},
+		{"list unknown post", "GET /posts/{id}/comments", http.MethodGet, "/posts/" + uuid.NewString() + "/comments", uuid.Nil, "", http.StatusNotFound},
+		{"list bad post id", "GET /posts/{id}/comments", http.MethodGet, "/posts/42/comments", uuid.Nil, "", http.StatusBadRequest},
+		{"create", "POST /posts/{id}/comments", http.MethodPost, "/posts/{post}/comments", author, `{"content": "yum"}`, http.StatusCreated},
+		{"create reply", "POST /posts/{id}/comments", http.MethodPost, "/posts/{post}/comments", author, `{"content": "yum", "parent_id": "{comment}"}`, http.StatusCreated},
+		{"create anonymous", "POST /posts/{id}/comments", http.MethodPost, "/posts/{post}/comments", uuid.Nil, `{"content": "yum"}`, http.StatusUnauthorized},
+		{"create empty", "POST /posts/{id}/comments", http.MethodPost, "/posts/{post}/comments", author, `{"content": ""}`, http.StatusUnprocessableEntity},
+		{"create reply to unknown comment", "POST /posts/{id}/comments", http.MethodPost, "/posts/{post}/comments", author, `{"content": "yum", "parent_id": "` + uuid.NewString() + `"}`, http.StatusNotFound},
+		{"create reply to deleted comment", "POST /posts/{id}/comments", http.MethodPost, "/posts/{post}/comments", author, `{"content": "yum", "parent_id": "{deleted}"}`, http.StatusConflict},
+		{"create on unknown post", "POST /posts/{id}/comments", http.MethodPost, "/posts/" + uuid.NewString() + "/comments", author, `{"content": "yum"}`, http.StatusNotFound},
+		{"replies", "GET /comments/{id}/replies", http.MethodGet, "/comments/{comment}/replies", uuid.Nil, "", http.StatusOK},
+		{"replies of unknown comment", "GET /comments/{id}/replies", http.MethodGet, "/comments/" + uuid.NewString() + "/replies", uuid.Nil, "", http.StatusNotFound},
+		{"edits", "GET /comments/{id}/edits", http.MethodGet, "/comments/{comment}/edits", uuid.Nil, "", http.StatusOK},
+		{"edits of unknown comment", "GET /comments/{id}/edits", http.MethodGet, "/comments/" + uuid.NewString() + "/edits", uuid.Nil, "", http.StatusNotFound},
+		{"update", "PUT /comments/{id}", http.MethodPut, "/comments/{comment}", author, `{"content": "edited"}`, http.StatusOK},
+		{"update by someone else", "PUT /comments/{id}", http.MethodPut, "/comments/{comment}", stranger, `{"content": "edited"}`, http.StatusForbidden},
+		{"delete by someone else", "DELETE /comments/{id}", http.MethodDelete, "/comments/{comment}", stranger, "", http.StatusForbidden},
+		{"delete", "DELETE /comments/{id}", http.MethodDelete, "/comments/{comment}", author, "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			posts := repository.NewMemoryPostRepository()
+			comments := repository.NewMemoryCommentRepository(posts, repository.NewMemorySocialRepository(posts))
+			h := NewCommentHandler(comments)
+
+			post := &models.Post{UserID: author}
+			if err := posts.Create(ctx, post); err != nil {
+				t.Fatal(err)
+			}
+			stored := &models.Comment{UserID: author, PostID: post.ID, Content: "first"}
+			if err := comments.Create(ctx, stored); err != nil {
+				t.Fatal(err)
+			}
+
+			deleted := &models.Comment{UserID: author, PostID: post.ID, Content: "gone"}
+			if err := comments.Create(ctx, deleted); err != nil {
+				t.Fatal(err)
+			}
+			if err := comments.SoftDelete(ctx, deleted.ID); err != nil {
+				t.Fatal(err)
+			}
+
+			handler := map[string]http.HandlerFunc{
+				"GET /posts/{id}/comments":   h.ListByPost,
+				"POST /posts/{id}/comments":  h.Create,
+				"GET /comments/{id}/replies": h.ListReplies,
+				"GET /comments/{id}/edits":   h.ListEdits,
+				"PUT /comments/{id}":         h.Update,
+				"DELETE /comments/{id}":      h.Delete,
+			}[tt.pattern]
+			replacer := strings.NewReplacer("{post}", post.ID.String(), "{comment}", stored.ID.String(), "{deleted}", deleted.ID.String())
+
+			rec := serve(t, tt.pattern, handler, tt.method, replacer.Replace(tt.target), tt.userID, replacer.Replace(tt.body))
+			if rec.Code != tt.want {
+				t.Errorf("%s %s = %d, want %d: %s", tt.method, tt.target, rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+}
+
+func TestCommentEditsKeepEarlierVersions(t *testing.T) {
+	ctx := context.Background()
+	author := uuid.New()
+	posts := repository.NewMemoryPostRepository()
+	comments := repository.NewMemoryCommentRepository(posts, repository.NewMemorySocialRepository(posts))
+	h := NewCommentHandler(comments)
+
+	post := &models.Post{UserID: author}
+	if err := posts.Create(ctx, post); err != nil {
+		t.Fatal(err)
+	}
+	stored := &models.Comment{UserID: author, PostID: post.ID, Content: "first"}
+	if err := comments.Create(ctx, stored); err != nil {
+		t.Fatal(err)
+	}
+	target := "/comments/" + stored.ID.String()
+
+	edits := func() []string {
+		t.Helper()
+		rec := serve(t, "GET /comments/{id}/edits", h.ListEdits, http.MethodGet, target+"/edits", uuid.Nil, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET edits = %d: %s", rec.Code, rec.Body)
+		}
+		var body struct {
+			Data []models.CommentEdit `json:"data"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		contents := []string{}
+		for _, edit := range body.Data {
+			if edit.CommentID != stored.ID {
+				t.Errorf("edit of comment %v, want %v", edit.CommentID, stored.ID)
+			}
+			contents = append(contents, edit.Content)
+		}
+		return contents
+	}
+
+	if got := edits(); len(got) != 0 {
+		t.Fatalf("edits before any edit = %v, want none", got)
+	}
+	for _, content := range []string{"second", "third"} {
+		rec := serve(t, "PUT /comments/{id}", h.Update, http.MethodPut, target, author, `{"content": "`+content+`"}`)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("PUT %q = %d: %s", content, rec.Code, rec.Body)
+		}
+	}
+	if got := edits(); !slices.Equal(got, []string{"first", "second"}) {
+		t.Errorf("edits = %v, want [first second]", got)
+	}
+
+	rec := serve(t, "DELETE /comments/{id}", h.Delete, http.MethodDelete, target, author, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("DELETE = %d: %s", rec.Code, rec.Body)
+	}
+	if got := edits(); len(got) != 0 {
+		t.Errorf("edits after delete = %v, want none", got)
+	}
+}
@@ -0,0 +1,339 @@
+// events.go contains the event lifecycle and RSVP handlers
+// anyone can browse events, the creator can edit/cancel them and see who is coming
+// RSVPs are capacity checked by the repository so events never go over max_attendees,
+// users asking to go to a full event are waitlisted and notified when they get promoted
+
+package handlers
+
+import (
+	"errors"
+	"feast-friends-api/internal/models"
+	"feast-friends-api/internal/repository"
+	"feast-friends-api/internal/services"
+	"feast-friends-api/internal/utils"
+	"feast-friends-api/pkg/logger"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// errNotEventCreator is returned when a user tries to manage someone elses event
+var errNotEventCreator = errors.New("user is not the creator of the event")
+
+// EventHandler serves the /events endpoints
+type EventHandler struct {
+	events   repository.EventRepository
+	notifier services.Notifier
+}
+
+// NewEventHandler creates the handler using the given event repository,
+// notifier is told about users promoted from the waitlist
+func NewEventHandler(events repository.EventRepository, notifier services.Notifier) *EventHandler {
+	return &EventHandler{events: events, notifier: notifier}
+}
+
+// eventRequest is the body accepted when creating or updating an event
+type eventRequest struct {
+	Title        string    `json:"title"`
+	Description  string    `json:"description"`
+	Location     string    `json:"location"`
+	EventDate    time.Time `json:"event_date"`
+	MaxAttendees int       `json:"max_attendees"`
+	ImageURL     string    `json:"image_url"`
+}
+
+// rsvpRequest is the body accepted by the RSVP endpoint
+type rsvpRequest struct {
+	Status string `json:"status"`
+}
+
+// waitlistRequest is the body accepted when reordering the waitlist
+type waitlistRequest struct {
+	UserIDs []uuid.UUID `json:"user_ids"`
+}
+
+// Create handles POST /events, the creator is the authenticated user
+func (h *EventHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID, err := currentUserID(r)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "Authentication required", err)
+		return
+	}
+
+	var req eventRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	event := &models.Event{CreatorID: userID}
+	req.apply(event)
+	if !validEvent(w, event) {
+		return
+	}
+
+	if err := h.events.Create(r.Context(), event); err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to create event", err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.SuccessResponse(event, "event created"))
+}
+
+// Get handles GET /events/{id}
+func (h *EventHandler) Get(w http.ResponseWriter, r *http.Request) {
+	event, ok := h.event(w, r)
+	if !ok {
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.SuccessResponse(event, "event found"))
+}
+
+// List handles GET /events, upcoming events soonest first
+func (h *EventHandler) List(w http.ResponseWriter, r *http.Request) {
+	page, limit, offset := pagination(r)
+
+	events, total, err := h.events.ListUpcoming(r.Context(), limit, offset)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to load events", err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.PaginatedResponse("events found", events, total, page, limit))
+}
+
+// Update handles PUT /events/{id}, only the creator can update the event
+func (h *EventHandler) Update(w http.ResponseWriter, r *http.Request) {
+	event, ok := h.ownedEvent(w, r)
+	if !ok {
+		return
+	}
+
+	var req eventRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	req.apply(event)
+	if !validEvent(w, event) {
+		return
+	}
+
+	promoted, err := h.events.Update(r.Context(), event)
+	if err != nil {
+		writeEventError(w, err, "Failed to update event")
+		return
+	}
+	h.notifyPromoted(r, *event, promoted)
+
+	utils.WriteJSON(w, http.StatusOK, utils.SuccessResponse(event, "event updated"))
+}
+
+// Cancel handles POST /events/{id}/cancel, only the creator can cancel the event
+func (h *EventHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	event, ok := h.ownedEvent(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.events.Cancel(r.Context(), event.ID); err != nil {
+		writeEventError(w, err, "Failed to cancel event")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.SuccessResponse(nil, "event cancelled"))
+}
+
+// RSVP handles PUT /events/{id}/rsvp with {"status": "going" | "maybe" | "cancelled"}
+// the legacy "attending" and "not_attending" are still accepted for older clients
+// going on a full event puts the user on the waitlist and answers 202 with their position
+func (h *EventHandler) RSVP(w http.ResponseWriter, r *http.Request) {
+	userID, err := currentUserID(r)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "Authentication required", err)
+		return
+	}
+	eventID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid event id", err)
+		return
+	}
+
+	var req rsvpRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	status, err := models.ParseRSVPStatus(req.Status)
+	if err != nil || !status.Requestable() {
+		utils.WriteJSON(w, http.StatusUnprocessableEntity, utils.FieldErrorsResponse("Validation failed",
+			map[string]string{"status": "must be one of: going maybe cancelled"}))
+		return
+	}
+
+	result, err := h.events.RSVP(r.Context(), eventID, userID, status)
+	if err != nil {
+		writeEventError(w, err, "Failed to save RSVP")
+		return
+	}
+	h.notifyPromoted(r, *result.Event, result.Promoted)
+
+	if result.Status == models.RSVPWaitlisted {
+		utils.WriteJSON(w, http.StatusAccepted, utils.SuccessResponse(result, "event is full, added to the waitlist"))
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.SuccessResponse(result, "rsvp saved"))
+}
+
+// Attendees handles GET /events/{id}/attendees, only the creator can see who is coming
+func (h *EventHandler) Attendees(w http.ResponseWriter, r *http.Request) {
+	event, ok := h.ownedEvent(w, r)
+	if !ok {
+		return
+	}
+	page, limit, offset := pagination(r)
+
+	attendees, total, err := h.events.ListAttendees(r.Context(), event.ID, limit, offset)
+	if err != nil {
+		writeEventError(w, err, "Failed to load attendees")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.PaginatedResponse("attendees found", attendees, total, page, limit))
+}
+
+// Waitlist handles GET /events/{id}/waitlist, only the creator can see it
+func (h *EventHandler) Waitlist(w http.ResponseWriter, r *http.Request) {
+	event, ok := h.ownedEvent(w, r)
+	if !ok {
+		return
+	}
+
+	waitlist, err := h.events.ListWaitlist(r.Context(), event.ID)
+	if err != nil {
+		writeEventError(w, err, "Failed to load waitlist")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.SuccessResponse(waitlist, "waitlist found"))
+}
+
+// ReorderWaitlist handles PUT /events/{id}/waitlist with {"user_ids": [...]}
+// the ids must be exactly the waitlisted users, the first one is promoted next
+func (h *EventHandler) ReorderWaitlist(w http.ResponseWriter, r *http.Request) {
+	event, ok := h.ownedEvent(w, r)
+	if !ok {
+		return
+	}
+
+	var req waitlistRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	if err := h.events.ReorderWaitlist(r.Context(), event.ID, req.UserIDs); err != nil {
+		writeEventError(w, err, "Failed to reorder waitlist")
+		return
+	}
+
+	waitlist, err := h.events.ListWaitlist(r.Context(), event.ID)
+	if err != nil {
+		writeEventError(w, err, "Failed to load waitlist")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.SuccessResponse(waitlist, "waitlist reordered"))
+}
+
+// notifyPromoted tells each promoted user they got a spot
+// the RSVP is already saved so failures are only logged
+func (h *EventHandler) notifyPromoted(r *http.Request, event models.Event, promoted []uuid.UUID) {
+	for _, userID := range promoted {
+		if err := h.notifier.WaitlistPromoted(r.Context(), event, userID); err != nil {
+			logger.ErrorContext(r.Context(), "Failed to notify user %v of waitlist promotion for event %v: %v", userID, event.ID, err)
+		}
+	}
+}
+
+// event loads the event from the {id} path param
+// it writes the error response itself and returns false when the request should stop
+func (h *EventHandler) event(w http.ResponseWriter, r *http.Request) (*models.Event, bool) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid event id", err)
+		return nil, false
+	}
+
+	event, err := h.events.GetByID(r.Context(), id)
+	if err != nil {
+		writeEventError(w, err, "Failed to load event")
+		return nil, false
+	}
+	return event, true
+}
+
+// ownedEvent loads the event from the {id} path param and checks the current user created it
+func (h *EventHandler) ownedEvent(w http.ResponseWriter, r *http.Request) (*models.Event, bool) {
+	userID, err := currentUserID(r)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "Authentication required", err)
+		return nil, false
+	}
+
+	event, ok := h.event(w, r)
+	if !ok {
+		return nil, false
+	}
+	if event.CreatorID != userID {
+		writeError(w, http.StatusForbidden, "Only the creator can manage this event", errNotEventCreator)
+		return nil, false
+	}
+	return event, true
+}
+
+// apply copies the editable fields of the request onto the event
+func (req eventRequest) apply(event *models.Event) {
+	event.Title = req.Title
+	event.Description = req.Description
+	event.Location = req.Location
+	event.EventDate = req.EventDate
+	event.MaxAttendees = req.MaxAttendees
+	event.ImageURL = req.ImageURL
+}
+
+// validEvent runs the model validation plus the checks tags cant express
+// it writes the 422 response itself and returns false when the event is invalid
+func validEvent(w http.ResponseWriter, event *models.Event) bool {
+	if err := event.Validate(); err != nil {
+		utils.WriteJSON(w, http.StatusUnprocessableEntity, utils.ValidationErrorResponse(err))
+		return false
+	}
+	if !event.EventDate.After(time.Now()) {
+		utils.WriteJSON(w, http.StatusUnprocessableEntity, utils.FieldErrorsResponse("Validation failed",
+			map[string]string{"event_date": "must be in the future"}))
+		return false
+	}
+	return true
+}
+
+// writeEventError maps repository errors to the matching status code
+func writeEventError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		writeError(w, http.StatusNotFound, "Event not found", err)
+	case errors.Is(err, repository.ErrEventFull):
+		writeError(w, http.StatusConflict, "Event is full", err)
+	case errors.Is(err, repository.ErrEventCancelled):
+		writeError(w, http.StatusConflict, "Event is cancelled", err)
+	case errors.Is(err, repository.ErrWaitlistMismatch):
+		utils.WriteJSON(w, http.StatusUnprocessableEntity, utils.FieldErrorsResponse("Validation failed",
+			map[string]string{"user_ids": "must list every waitlisted user exactly once"}))
+	case errors.Is(err, repository.ErrCapacityTooLow):
+		writeError(w, http.StatusConflict, "Max attendees cannot be lower than the current attendees", err)
+	default:
+		writeError(w, http.StatusInternalServerError, fallback, err)
+	}
+}
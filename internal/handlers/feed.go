@@ -0,0 +1,52 @@
+// feed.go contains the home feed handlers
+// the following feed uses cursor pagination: clients send back meta.next_cursor to get the next page
+
+package handlers
+
+import (
+	"feast-friends-api/internal/repository"
+	"feast-friends-api/internal/utils"
+	"net/http"
+)
+
+// FeedHandler serves the /feed endpoints
+type FeedHandler struct {
+	feed repository.FeedRepository
+}
+
+// NewFeedHandler creates the handler using the given feed repository
+func NewFeedHandler(feed repository.FeedRepository) *FeedHandler {
+	return &FeedHandler{feed: feed}
+}
+
+// Following handles GET /feed/following?cursor=&limit=
+// posts from everyone the authenticated user follows, newest first
+func (h *FeedHandler) Following(w http.ResponseWriter, r *http.Request) {
+	userID, err := currentUserID(r)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "Authentication required", err)
+		return
+	}
+
+	after, limit, err := cursorPagination(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid cursor", err)
+		return
+	}
+
+	// ask for one extra post to know if there is a next page without a count query
+	posts, err := h.feed.ListFollowing(r.Context(), userID, after, limit+1)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to load feed", err)
+		return
+	}
+
+	nextCursor := ""
+	if len(posts) > limit {
+		posts = posts[:limit]
+		last := posts[len(posts)-1]
+		nextCursor = utils.EncodeCursor(utils.Cursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.CursorPaginatedResponse("feed loaded", posts, limit, nextCursor))
+}
@@ -0,0 +1,87 @@
+// Package handlers contains the HTTP handlers of the api.
+// handlers decode the request, call the repository layer and write the response
+// using the helpers in utils so every endpoint returns the same JSON shape
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"feast-friends-api/internal/middleware"
+	"feast-friends-api/internal/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// errUnauthenticated is returned when a protected handler runs without a user in the context
+var errUnauthenticated = errors.New("no authenticated user in request context")
+
+// currentUserID reads the user id AuthMiddleware stored in the request context
+func currentUserID(r *http.Request) (uuid.UUID, error) {
+	id, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		return uuid.Nil, errUnauthenticated
+	}
+	return id, nil
+}
+
+// pathID parses the named path parameter (e.g. {id}) as a uuid
+func pathID(r *http.Request, name string) (uuid.UUID, error) {
+	return uuid.Parse(r.PathValue(name))
+}
+
+// pagination reads ?page= and ?limit= from the query string
+// page starts at 1, limit defaults to 20 and is capped at 100
+func pagination(r *http.Request) (page, limit, offset int) {
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	limit, err = strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit < 1 {
+		limit = defaultPageLimit
+	}
+	if limit > maxPageLimit {
+		limit = maxPageLimit
+	}
+	return page, limit, (page - 1) * limit
+}
+
+// cursorPagination reads ?cursor= and ?limit= for keyset paginated lists
+// after is nil on the first page, limit defaults to 20 and is capped at 100
+func cursorPagination(r *http.Request) (after *utils.Cursor, limit int, err error) {
+	limit, err = strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit < 1 {
+		limit = defaultPageLimit
+	}
+	if limit > maxPageLimit {
+		limit = maxPageLimit
+	}
+
+	if raw := r.URL.Query().Get("cursor"); raw != "" {
+		cursor, err := utils.DecodeCursor(raw)
+		if err != nil {
+			return nil, limit, err
+		}
+		after = &cursor
+	}
+	return after, limit, nil
+}
+
+// decodeJSON decodes the request body into dst and rejects unknown fields
+func decodeJSON(r *http.Request, dst interface{}) error {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(dst)
+}
+
+// writeError writes an ErrorResponse with the given status code
+func writeError(w http.ResponseWriter, statusCode int, message string, err error) {
+	utils.WriteJSON(w, statusCode, utils.ErrorResponse(message, err, statusCode))
+}
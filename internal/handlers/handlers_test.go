@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"context"
+	"feast-friends-api/internal/middleware"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// serve routes one request to handler registered under pattern, as userID when it is not uuid.Nil
+func serve(t *testing.T, pattern string, handler http.HandlerFunc, method, target string, userID uuid.UUID, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc(pattern, handler)
+
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if userID != uuid.Nil {
+		req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, userID.String()))
+	}
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestPagination(t *testing.T) {
+	tests := []struct {
+		query  string
+		page   int
+		limit  int
+		offset int
+	}{
+		{"", 1, defaultPageLimit, 0},
+		{"?page=3&limit=10", 3, 10, 20},
+		{"?page=0&limit=0", 1, defaultPageLimit, 0},
+		{"?page=-2&limit=-5", 1, defaultPageLimit, 0},
+		{"?page=abc&limit=xyz", 1, defaultPageLimit, 0},
+		{"?page=2&limit=1000", 2, maxPageLimit, maxPageLimit},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			page, limit, offset := pagination(httptest.NewRequest(http.MethodGet, "/posts"+tt.query, nil))
+			if page != tt.page || limit != tt.limit || offset != tt.offset {
+				t.Errorf("pagination(%q) = (%d, %d, %d), want (%d, %d, %d)", tt.query, page, limit, offset, tt.page, tt.limit, tt.offset)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+// health.go contains the health check handlers used by load balancers, orchestrators and uptime checks
+// they are registered under /health which the Logs middleware skips so probes dont flood the logs
+// /health/live only says the process answers, /health/ready also checks the db and supabase
+
+package handlers
+
+import (
+	"feast-friends-api/internal/health"
+	"feast-friends-api/internal/utils"
+	"net/http"
+)
+
+// Health reports that the server process is up and able to answer requests
+func Health(w http.ResponseWriter, r *http.Request) {
+	utils.WriteJSON(w, http.StatusOK, utils.SuccessResponse(map[string]string{"status": "ok"}, "server is healthy"))
+}
+
+// HealthHandler serves the liveness and readiness probes
+type HealthHandler struct {
+	checker *health.Checker
+}
+
+// NewHealthHandler creates a HealthHandler, the readiness probe reports the checker's dependencies
+func NewHealthHandler(checker *health.Checker) *HealthHandler {
+	return &HealthHandler{checker: checker}
+}
+
+// Live reports that the process is running, it never checks dependencies so a db outage
+// does not get every replica restarted
+func (h *HealthHandler) Live(w http.ResponseWriter, r *http.Request) {
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"status": health.StatusOK})
+}
+
+// Ready reports whether the server can take traffic, with the status and latency of each dependency.
+// It answers 503 when a check fails or once graceful shutdown started.
+func (h *HealthHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	report := h.checker.Report(r.Context())
+	status := http.StatusOK
+	if !report.OK() {
+		status = http.StatusServiceUnavailable
+	}
+	utils.WriteJSON(w, status, report)
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"context"
+	"feast-friends-api/internal/config"
+	"feast-friends-api/internal/health"
+	"feast-friends-api/internal/utils"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestHealthReadyChecksSupabase(t *testing.T) {
+	tests := []struct {
+		name   string
+		gotrue http.HandlerFunc
+		want   int
+	}{
+		{
+			name: "healthy",
+			gotrue: func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/auth/v1/health" || r.Header.Get("apikey") != "anon-key" || r.Header.Get("Authorization") != "" {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				w.Write([]byte(`{"version":"v2","name":"GoTrue","description":"GoTrue is a user registration and authentication API"}`))
+			},
+			want: http.StatusOK,
+		},
+		{
+			name:   "failing",
+			gotrue: func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusBadGateway) },
+			want:   http.StatusServiceUnavailable,
+		},
+		{
+			name:   "slow",
+			gotrue: func(w http.ResponseWriter, r *http.Request) { time.Sleep(300 * time.Millisecond) },
+			want:   http.StatusServiceUnavailable,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotrue := httptest.NewServer(tt.gotrue)
+			t.Cleanup(gotrue.Close)
+
+			cfg := config.Get()
+			previous := cfg.Supabase
+			cfg.Supabase.AuthURL = gotrue.URL + "/auth/v1"
+			cfg.Supabase.AKey = "anon-key"
+			t.Cleanup(func() { cfg.Supabase = previous })
+
+			checker := health.NewChecker(100*time.Millisecond, time.Minute,
+				health.Check{Name: "supabase", Run: utils.PingSupabase},
+				health.Check{Name: "database", Run: func(ctx context.Context) error { return nil }},
+			)
+			rec := serve(t, "GET /health/ready", NewHealthHandler(checker).Ready, http.MethodGet, "/health/ready", uuid.Nil, "")
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,257 @@
+// messages.go contains the direct messaging handlers
+// every endpoint is for participants only, other users get 404 so they cannot probe which conversations exist
+// message history uses cursor pagination like the feed, the inbox uses page/limit
+// new messages, read receipts and typing indicators are also pushed to the realtime hub
+
+package handlers
+
+import (
+	"errors"
+	"feast-friends-api/internal/models"
+	"feast-friends-api/internal/realtime"
+	"feast-friends-api/internal/repository"
+	"feast-friends-api/internal/utils"
+	"feast-friends-api/pkg/logger"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// errNotParticipant is returned when a user opens someone elses conversation
+var errNotParticipant = errors.New("user is not a participant of the conversation")
+
+// MessageHandler serves the /conversations endpoints
+type MessageHandler struct {
+	messages repository.MessageRepository
+	hub      realtime.Hub
+}
+
+// NewMessageHandler creates the handler using the given message repository,
+// events are published on hub for the participants connected to the realtime stream
+func NewMessageHandler(messages repository.MessageRepository, hub realtime.Hub) *MessageHandler {
+	return &MessageHandler{messages: messages, hub: hub}
+}
+
+// openConversationRequest is the body accepted when opening a conversation
+type openConversationRequest struct {
+	UserID uuid.UUID `json:"user_id"`
+}
+
+// messageRequest is the body accepted when sending a message
+type messageRequest struct {
+	Content     string `json:"content"`
+	MessageType string `json:"message_type"`
+}
+
+// readResponse is returned after marking a conversation read
+type readResponse struct {
+	Marked int `json:"marked"`
+}
+
+// Open handles POST /conversations with {"user_id": "<uuid>"}
+// returns 201 when the conversation was created and 200 when it already existed
+func (h *MessageHandler) Open(w http.ResponseWriter, r *http.Request) {
+	userID, err := currentUserID(r)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "Authentication required", err)
+		return
+	}
+
+	var req openConversationRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if req.UserID == uuid.Nil || req.UserID == userID {
+		utils.WriteJSON(w, http.StatusUnprocessableEntity, utils.FieldErrorsResponse("Validation failed",
+			map[string]string{"user_id": "must be another user"}))
+		return
+	}
+
+	conversation, created, err := h.messages.OpenConversation(r.Context(), userID, req.UserID)
+	if errors.Is(err, repository.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "User not found", err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to open conversation", err)
+		return
+	}
+
+	if created {
+		utils.WriteJSON(w, http.StatusCreated, utils.SuccessResponse(conversation, "conversation created"))
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.SuccessResponse(conversation, "conversation found"))
+}
+
+// Inbox handles GET /conversations?page=&limit=, most recent activity first
+func (h *MessageHandler) Inbox(w http.ResponseWriter, r *http.Request) {
+	userID, err := currentUserID(r)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "Authentication required", err)
+		return
+	}
+	page, limit, offset := pagination(r)
+
+	inbox, total, err := h.messages.ListInbox(r.Context(), userID, limit, offset)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to load conversations", err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.PaginatedResponse("conversations found", inbox, total, page, limit))
+}
+
+// List handles GET /conversations/{id}/messages?cursor=&limit=, newest first
+func (h *MessageHandler) List(w http.ResponseWriter, r *http.Request) {
+	conversation, _, ok := h.participantConversation(w, r)
+	if !ok {
+		return
+	}
+
+	after, limit, err := cursorPagination(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid cursor", err)
+		return
+	}
+
+	// ask for one extra message to know if there is a next page without a count query
+	messages, err := h.messages.ListMessages(r.Context(), conversation.ID, after, limit+1)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to load messages", err)
+		return
+	}
+
+	nextCursor := ""
+	if len(messages) > limit {
+		messages = messages[:limit]
+		last := messages[len(messages)-1]
+		nextCursor = utils.EncodeCursor(utils.Cursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.CursorPaginatedResponse("messages found", messages, limit, nextCursor))
+}
+
+// Send handles POST /conversations/{id}/messages, message_type defaults to text
+func (h *MessageHandler) Send(w http.ResponseWriter, r *http.Request) {
+	conversation, userID, ok := h.participantConversation(w, r)
+	if !ok {
+		return
+	}
+
+	var req messageRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if req.MessageType == "" {
+		req.MessageType = models.MessageText
+	}
+
+	message := &models.Message{
+		ConversationID: conversation.ID,
+		SenderID:       userID,
+		Content:        req.Content,
+		MessageType:    req.MessageType,
+	}
+	if err := message.Validate(); err != nil {
+		utils.WriteJSON(w, http.StatusUnprocessableEntity, utils.ValidationErrorResponse(err))
+		return
+	}
+
+	if err := h.messages.SendMessage(r.Context(), message); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "Conversation not found", err)
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "Failed to send message", err)
+		return
+	}
+
+	// the sender gets it too so their other devices stay in sync
+	h.publish(r, []uuid.UUID{conversation.User1ID, conversation.User2ID}, realtime.Event{
+		Type:           realtime.EventMessageCreated,
+		ConversationID: conversation.ID,
+		UserID:         userID,
+		Message:        message,
+	})
+
+	utils.WriteJSON(w, http.StatusCreated, utils.SuccessResponse(message, "message sent"))
+}
+
+// MarkRead handles POST /conversations/{id}/read, every message from the other user is marked read
+func (h *MessageHandler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	conversation, userID, ok := h.participantConversation(w, r)
+	if !ok {
+		return
+	}
+
+	marked, err := h.messages.MarkRead(r.Context(), conversation.ID, userID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to mark messages read", err)
+		return
+	}
+
+	if marked > 0 {
+		readAt := time.Now().UTC()
+		h.publish(r, []uuid.UUID{conversation.OtherParticipant(userID)}, realtime.Event{
+			Type:           realtime.EventMessagesRead,
+			ConversationID: conversation.ID,
+			UserID:         userID,
+			ReadAt:         &readAt,
+		})
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.SuccessResponse(readResponse{Marked: marked}, "messages marked read"))
+}
+
+// Typing handles POST /conversations/{id}/typing, for clients on the SSE stream that cannot send over it
+func (h *MessageHandler) Typing(w http.ResponseWriter, r *http.Request) {
+	conversation, userID, ok := h.participantConversation(w, r)
+	if !ok {
+		return
+	}
+
+	publishTyping(r.Context(), h.hub, conversation, userID)
+	utils.WriteJSON(w, http.StatusOK, utils.SuccessResponse(nil, "typing sent"))
+}
+
+// publish sends the event to the recipients, the write already succeeded so failures are only logged
+func (h *MessageHandler) publish(r *http.Request, recipients []uuid.UUID, event realtime.Event) {
+	if err := h.hub.Publish(r.Context(), recipients, event); err != nil {
+		logger.ErrorContext(r.Context(), "failed to publish %s event for conversation %v: %v", event.Type, event.ConversationID, err)
+	}
+}
+
+// participantConversation loads the conversation from the {id} path param and checks the current user is part of it
+// it writes the error response itself and returns false when the request should stop
+func (h *MessageHandler) participantConversation(w http.ResponseWriter, r *http.Request) (*models.Conversation, uuid.UUID, bool) {
+	userID, err := currentUserID(r)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "Authentication required", err)
+		return nil, uuid.Nil, false
+	}
+	id, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid conversation id", err)
+		return nil, uuid.Nil, false
+	}
+
+	conversation, err := h.messages.GetConversation(r.Context(), id)
+	if errors.Is(err, repository.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "Conversation not found", err)
+		return nil, uuid.Nil, false
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to load conversation", err)
+		return nil, uuid.Nil, false
+	}
+
+	if !conversation.HasParticipant(userID) {
+		writeError(w, http.StatusNotFound, "Conversation not found", errNotParticipant)
+		return nil, uuid.Nil, false
+	}
+	return conversation, userID, true
+}
@@ -0,0 +1,245 @@
+// posts.go contains the CRUD handlers for recipe posts
+// anyone can read posts, only the authenticated author can edit or delete them
+// reads go through OptionalAuth, when the request has a token the posts say whether the user likes them
+
+package handlers
+
+import (
+	"errors"
+	"feast-friends-api/internal/middleware"
+	"feast-friends-api/internal/models"
+	"feast-friends-api/internal/repository"
+	"feast-friends-api/internal/utils"
+	"feast-friends-api/pkg/logger"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// errNotPostOwner is returned when a user tries to change someone elses post
+var errNotPostOwner = errors.New("user is not the author of the post")
+
+// PostHandler serves the /posts endpoints
+type PostHandler struct {
+	posts repository.PostRepository
+	likes repository.SocialRepository
+}
+
+// NewPostHandler creates the handler using the given post repository, likes is used for liked_by_me
+func NewPostHandler(posts repository.PostRepository, likes repository.SocialRepository) *PostHandler {
+	return &PostHandler{posts: posts, likes: likes}
+}
+
+// postRequest is the body accepted when creating or updating a post
+type postRequest struct {
+	Title       string        `json:"title"`
+	Description string        `json:"description"`
+	ImageURL    string        `json:"image_url"`
+	Recipe      models.Recipe `json:"recipe"`
+}
+
+// Create handles POST /posts, the author is the authenticated user
+func (h *PostHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID, err := currentUserID(r)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "Authentication required", err)
+		return
+	}
+
+	var req postRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	post := &models.Post{
+		UserID:      userID,
+		Title:       req.Title,
+		Description: req.Description,
+		ImageURL:    req.ImageURL,
+		Recipe:      req.Recipe,
+	}
+	if err := post.Validate(); err != nil {
+		utils.WriteJSON(w, http.StatusUnprocessableEntity, utils.ValidationErrorResponse(err))
+		return
+	}
+
+	if err := h.posts.Create(r.Context(), post); err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to create post", err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.SuccessResponse(post, "post created"))
+}
+
+// Get handles GET /posts/{id}
+func (h *PostHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid post id", err)
+		return
+	}
+
+	post, err := h.posts.GetByID(r.Context(), id)
+	if errors.Is(err, repository.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "Post not found", err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to load post", err)
+		return
+	}
+	h.markLiked(r, []*models.Post{post})
+
+	utils.WriteJSON(w, http.StatusOK, utils.SuccessResponse(post, "post found"))
+}
+
+// List handles GET /posts, every post newest first with page/limit pagination
+func (h *PostHandler) List(w http.ResponseWriter, r *http.Request) {
+	page, limit, offset := pagination(r)
+
+	posts, total, err := h.posts.ListFeed(r.Context(), limit, offset)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to load posts", err)
+		return
+	}
+	h.markLiked(r, postRefs(posts))
+
+	utils.WriteJSON(w, http.StatusOK, utils.PaginatedResponse("posts found", posts, total, page, limit))
+}
+
+// ListByUser handles GET /users/{id}/posts
+func (h *PostHandler) ListByUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid user id", err)
+		return
+	}
+	page, limit, offset := pagination(r)
+
+	posts, total, err := h.posts.ListByUser(r.Context(), userID, limit, offset)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to load posts", err)
+		return
+	}
+	h.markLiked(r, postRefs(posts))
+
+	utils.WriteJSON(w, http.StatusOK, utils.PaginatedResponse("posts found", posts, total, page, limit))
+}
+
+// Update handles PUT /posts/{id}, only the author can update the post
+func (h *PostHandler) Update(w http.ResponseWriter, r *http.Request) {
+	post, ok := h.ownedPost(w, r)
+	if !ok {
+		return
+	}
+
+	var req postRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	post.Title = req.Title
+	post.Description = req.Description
+	post.ImageURL = req.ImageURL
+	post.Recipe = req.Recipe
+	if err := post.Validate(); err != nil {
+		utils.WriteJSON(w, http.StatusUnprocessableEntity, utils.ValidationErrorResponse(err))
+		return
+	}
+
+	if err := h.posts.Update(r.Context(), post); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "Post not found", err)
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "Failed to update post", err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.SuccessResponse(post, "post updated"))
+}
+
+// Delete handles DELETE /posts/{id}, only the author can delete the post
+func (h *PostHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	post, ok := h.ownedPost(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.posts.Delete(r.Context(), post.ID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "Post not found", err)
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "Failed to delete post", err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.SuccessResponse(nil, "post deleted"))
+}
+
+// ownedPost loads the post from the {id} path param and checks the current user wrote it
+// it writes the error response itself and returns false when the request should stop
+func (h *PostHandler) ownedPost(w http.ResponseWriter, r *http.Request) (*models.Post, bool) {
+	userID, err := currentUserID(r)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "Authentication required", err)
+		return nil, false
+	}
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid post id", err)
+		return nil, false
+	}
+
+	post, err := h.posts.GetByID(r.Context(), id)
+	if errors.Is(err, repository.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "Post not found", err)
+		return nil, false
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to load post", err)
+		return nil, false
+	}
+
+	if post.UserID != userID {
+		writeError(w, http.StatusForbidden, "Only the author can modify this post", errNotPostOwner)
+		return nil, false
+	}
+	return post, true
+}
+
+// markLiked sets LikedByMe on the posts when OptionalAuth found a user, anonymous requests leave it unset
+// a failure only loses the personalisation, the posts are still returned
+func (h *PostHandler) markLiked(r *http.Request, posts []*models.Post) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok || len(posts) == 0 {
+		return
+	}
+
+	ids := make([]uuid.UUID, len(posts))
+	for i, post := range posts {
+		ids[i] = post.ID
+	}
+	liked, err := h.likes.LikedPosts(r.Context(), userID, ids)
+	if err != nil {
+		logger.ErrorContext(r.Context(), "failed to load liked_by_me for user %v: %v", userID, err)
+		return
+	}
+	for _, post := range posts {
+		isLiked := liked[post.ID]
+		post.LikedByMe = &isLiked
+	}
+}
+
+// postRefs points into the slice so markLiked can update the posts in place
+func postRefs(posts []models.Post) []*models.Post {
+	refs := make([]*models.Post, len(posts))
+	for i := range posts {
+		refs[i] = &posts[i]
+	}
+	return refs
+}
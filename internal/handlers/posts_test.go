@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"context"
+	"feast-friends-api/internal/models"
+	"feast-friends-api/internal/repository"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+const validPostBody = `{"title": "Pancakes", "recipe": {"ingredients": [{"name": "flour", "quantity": "200g"}], "instructions": ["mix", "fry"]}}`
+
+func TestPostHandlerStatusCodes(t *testing.T) {
+	author := uuid.New()
+	stranger := uuid.New()
+
+	tests := []struct {
+		name    string
+		pattern string
+		method  string
+		target  string // {post} is replaced with the id of a stored post
+		userID  uuid.UUID
+		body    string
+		want    int
+	}{
+		{"create", "POST /posts", http.MethodPost, "/posts", author, validPostBody, http.StatusCreated},
+		{"create anonymous", "POST /posts", http.MethodPost, "/posts", uuid.Nil, validPostBody, http.StatusUnauthorized},
+		{"create bad json", "POST /posts", http.MethodPost, "/posts", author, `{"title":`, http.StatusBadRequest},
+		{"create unknown field", "POST /posts", http.MethodPost, "/posts", author, `{"name": "Pancakes"}`, http.StatusBadRequest},
+		{"create without recipe", "POST /posts", http.MethodPost, "/posts", author, `{"title": "Pancakes"}`, http.StatusUnprocessableEntity},
+		{"get", "GET /posts/{id}", http.MethodGet, "/posts/{post}", uuid.Nil, "", http.StatusOK},
+		{"get unknown", "GET /posts/{id}", http.MethodGet, "/posts/" + uuid.NewString(), uuid.Nil, "", http.StatusNotFound},
+		{"get bad id", "GET /posts/{id}", http.MethodGet, "/posts/42", uuid.Nil, "", http.StatusBadRequest},
+		{"list", "GET /posts", http.MethodGet, "/posts?page=1&limit=5", uuid.Nil, "", http.StatusOK},
+		{"update", "PUT /posts/{id}", http.MethodPut, "/posts/{post}", author, validPostBody, http.StatusOK},
+		{"update by someone else", "PUT /posts/{id}", http.MethodPut, "/posts/{post}", stranger, validPostBody, http.StatusForbidden},
+		{"update anonymous", "PUT /posts/{id}", http.MethodPut, "/posts/{post}", uuid.Nil, validPostBody, http.StatusUnauthorized},
+		{"update unknown", "PUT /posts/{id}", http.MethodPut, "/posts/" + uuid.NewString(), author, validPostBody, http.StatusNotFound},
+		{"update invalid", "PUT /posts/{id}", http.MethodPut, "/posts/{post}", author, `{"recipe": {"ingredients": [], "instructions": []}}`, http.StatusUnprocessableEntity},
+		{"delete", "DELETE /posts/{id}", http.MethodDelete, "/posts/{post}", author, "", http.StatusOK},
+		{"delete by someone else", "DELETE /posts/{id}", http.MethodDelete, "/posts/{post}", stranger, "", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			posts := repository.NewMemoryPostRepository()
+			h := NewPostHandler(posts, repository.NewMemorySocialRepository(posts))
+			post := &models.Post{UserID: author, Recipe: models.Recipe{
+				Ingredients:  []models.Ingredients{{Name: "flour", Quantity: "200g"}},
+				Instructions: []string{"mix"},
+			}}
+			if err := posts.Create(context.Background(), post); err != nil {
+				t.Fatal(err)
+			}
+
+			handler := map[string]http.HandlerFunc{
+				"POST /posts":        h.Create,
+				"GET /posts":         h.List,
+				"GET /posts/{id}":    h.Get,
+				"PUT /posts/{id}":    h.Update,
+				"DELETE /posts/{id}": h.Delete,
+			}[tt.pattern]
+			target := strings.ReplaceAll(tt.target, "{post}", post.ID.String())
+
+			rec := serve(t, tt.pattern, handler, tt.method, target, tt.userID, tt.body)
+			if rec.Code != tt.want {
+				t.Errorf("%s %s = %d, want %d: %s", tt.method, target, rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+// realtime.go contains the realtime stream endpoint
+// clients connect with a WebSocket, or with server sent events (EventSource) when WebSockets are not available,
+// and receive realtime.Event objects for the conversations they are part of.
+// over a WebSocket clients can also send {"type": "typing", "conversation_id": "<uuid>"}, SSE clients
+// use POST /conversations/{id}/typing instead
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"feast-friends-api/internal/models"
+	"feast-friends-api/internal/realtime"
+	"feast-friends-api/internal/repository"
+	"feast-friends-api/pkg/logger"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// writeWait is how long a single write to the client may take
+	writeWait = 10 * time.Second
+	// pongWait is how long a WebSocket may stay silent before it is considered dead
+	pongWait = 60 * time.Second
+	// pingPeriod must be shorter than pongWait so live clients always answer in time
+	pingPeriod = 30 * time.Second
+	// maxClientMessage limits what clients can send over the WebSocket
+	maxClientMessage = 1024
+)
+
+// RealtimeHandler serves the realtime stream
+type RealtimeHandler struct {
+	hub      realtime.Hub
+	messages repository.MessageRepository
+	upgrader websocket.Upgrader
+}
+
+// NewRealtimeHandler creates the handler, WebSocket connections are only accepted from the origins
+// allowOrigin accepts (the CORS policy) or from clients that do not send an Origin header
+func NewRealtimeHandler(hub realtime.Hub, messages repository.MessageRepository, allowOrigin func(origin string) bool) *RealtimeHandler {
+	return &RealtimeHandler{
+		hub:      hub,
+		messages: messages,
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+			CheckOrigin: func(r *http.Request) bool {
+				origin := r.Header.Get("Origin")
+				return origin == "" || allowOrigin(origin)
+			},
+		},
+	}
+}
+
+// clientEvent is what clients can send over the WebSocket
+type clientEvent struct {
+	Type           string    `json:"type"`
+	ConversationID uuid.UUID `json:"conversation_id"`
+}
+
+// Stream handles GET /realtime, upgrading to a WebSocket when asked and streaming SSE otherwise
+// the token can be sent as ?access_token= because browsers cannot set headers on these requests
+func (h *RealtimeHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	userID, err := currentUserID(r)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "Authentication required", err)
+		return
+	}
+
+	if websocket.IsWebSocketUpgrade(r) {
+		h.serveWebSocket(w, r, userID)
+		return
+	}
+	h.serveSSE(w, r, userID)
+}
+
+// serveWebSocket pushes events from the hub and reads typing indicators until either side closes
+// all writes happen in the writer goroutine because a websocket.Conn allows a single writer
+func (h *RealtimeHandler) serveWebSocket(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// the upgrader already wrote the error response
+		logger.WarnContext(r.Context(), "websocket upgrade failed for user %v: %v", userID, err)
+		return
+	}
+	defer conn.Close()
+
+	events, unsubscribe := h.hub.Subscribe(userID)
+	defer unsubscribe()
+
+	done := make(chan struct{})
+	go func() {
+		defer conn.Close() // unblocks the reader below when the writer gives up
+		ticker := time.NewTicker(pingPeriod)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case event, ok := <-events:
+				conn.SetWriteDeadline(time.Now().Add(writeWait))
+				if !ok {
+					conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"))
+					return
+				}
+				if err := conn.WriteJSON(event); err != nil {
+					return
+				}
+			case <-ticker.C:
+				conn.SetWriteDeadline(time.Now().Add(writeWait))
+				if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+					return
+				}
+			}
+		}
+	}()
+	defer close(done)
+
+	conn.SetReadLimit(maxClientMessage)
+	conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		var event clientEvent
+		if err := conn.ReadJSON(&event); err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				logger.DebugContext(r.Context(), "websocket of user %v closed: %v", userID, err)
+			}
+			return
+		}
+		conn.SetReadDeadline(time.Now().Add(pongWait))
+
+		if event.Type != realtime.EventTyping {
+			logger.DebugContext(r.Context(), "ignoring %q event from user %v", event.Type, userID)
+			continue
+		}
+		conversation, err := h.messages.GetConversation(r.Context(), event.ConversationID)
+		if err != nil || !conversation.HasParticipant(userID) {
+			continue
+		}
+		publishTyping(r.Context(), h.hub, conversation, userID)
+	}
+}
+
+// serveSSE streams events as server sent events until the client disconnects or the hub is closed
+func (h *RealtimeHandler) serveSSE(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	rc := http.NewResponseController(w)
+	// the stream is long lived, the server WriteTimeout would cut it otherwise
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		logger.WarnContext(r.Context(), "failed to clear write deadline for sse stream: %v", err)
+	}
+
+	events, unsubscribe := h.hub.Subscribe(userID)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // stop nginx from buffering the stream
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		logger.ErrorContext(r.Context(), "sse stream is not supported by the response writer: %v", err)
+		return
+	}
+
+	ticker := time.NewTicker(pingPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				logger.ErrorContext(r.Context(), "failed to encode %s event: %v", event.Type, err)
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data); err != nil {
+				return
+			}
+		case <-ticker.C:
+			// comment lines keep proxies from closing an idle stream
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// publishTyping tells the other participant that userID is typing
+func publishTyping(ctx context.Context, hub realtime.Hub, conversation *models.Conversation, userID uuid.UUID) {
+	event := realtime.Event{Type: realtime.EventTyping, ConversationID: conversation.ID, UserID: userID}
+	if err := hub.Publish(ctx, []uuid.UUID{conversation.OtherParticipant(userID)}, event); err != nil {
+		logger.ErrorContext(ctx, "failed to publish typing event for conversation %v: %v", conversation.ID, err)
+	}
+}
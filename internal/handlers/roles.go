@@ -0,0 +1,122 @@
+// roles.go lets admins manage the roles of other users
+// tokens issued by the api pick up new roles on their next refresh, removing a role also ends the
+// sessions of the user and RequireRole checks the stored roles so it stops working right away
+
+package handlers
+
+import (
+	"errors"
+	"feast-friends-api/internal/auth"
+	"feast-friends-api/internal/repository"
+	"feast-friends-api/internal/utils"
+	"net/http"
+)
+
+// RoleHandler serves the role endpoints, the routes are wrapped with RequireRole(admin)
+type RoleHandler struct {
+	profiles repository.ProfileRepository
+	tokens   *auth.TokenService
+}
+
+// NewRoleHandler creates the handler using the given profile repository and token service
+func NewRoleHandler(profiles repository.ProfileRepository, tokens *auth.TokenService) *RoleHandler {
+	return &RoleHandler{profiles: profiles, tokens: tokens}
+}
+
+// rolesRequest is the body accepted when setting the roles of a user
+type rolesRequest struct {
+	Roles []string `json:"roles"`
+}
+
+// Get handles GET /users/{id}/roles
+func (h *RoleHandler) Get(w http.ResponseWriter, r *http.Request) {
+	userID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid user id", err)
+		return
+	}
+
+	roles, err := h.profiles.Roles(r.Context(), userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "User not found", err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to get roles", err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.SuccessResponse(rolesRequest{Roles: roles}, "roles retrieved"))
+}
+
+// Set handles PUT /users/{id}/roles with {"roles": ["moderator"]}, the list replaces the current roles
+// "user" is always kept and admins cannot drop their own admin role so the api is never left without one
+func (h *RoleHandler) Set(w http.ResponseWriter, r *http.Request) {
+	currentID, err := currentUserID(r)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized", err)
+		return
+	}
+	userID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid user id", err)
+		return
+	}
+
+	var req rolesRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	roles := []string{auth.RoleUser}
+	seen := map[string]bool{auth.RoleUser: true}
+	for _, role := range req.Roles {
+		if !auth.ValidRole(role) {
+			utils.WriteJSON(w, http.StatusUnprocessableEntity, utils.FieldErrorsResponse("Validation failed",
+				map[string]string{"roles": "unknown role " + role + ", must be user, moderator or admin"}))
+			return
+		}
+		if !seen[role] {
+			seen[role] = true
+			roles = append(roles, role)
+		}
+	}
+	if userID == currentID && !seen[auth.RoleAdmin] {
+		utils.WriteJSON(w, http.StatusUnprocessableEntity, utils.FieldErrorsResponse("Validation failed",
+			map[string]string{"roles": "admins cannot remove their own admin role"}))
+		return
+	}
+
+	previous, err := h.profiles.Roles(r.Context(), userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "User not found", err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to set roles", err)
+		return
+	}
+
+	err = h.profiles.SetRoles(r.Context(), userID, roles)
+	if errors.Is(err, repository.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "User not found", err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to set roles", err)
+		return
+	}
+
+	// a demoted user has to log in again, no session started while they held the role survives it.
+	// access tokens already handed out still live until they expire
+	for _, role := range previous {
+		if !seen[role] {
+			if err := h.tokens.RevokeUser(r.Context(), userID); err != nil {
+				writeError(w, http.StatusInternalServerError, "Failed to end the sessions of the user", err)
+				return
+			}
+			break
+		}
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.SuccessResponse(rolesRequest{Roles: roles}, "roles updated"))
+}
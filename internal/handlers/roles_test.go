@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"feast-friends-api/internal/auth"
+	"feast-friends-api/internal/middleware"
+	"feast-friends-api/internal/models"
+	"feast-friends-api/internal/repository"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestRoleHandlerSet(t *testing.T) {
+	admin := uuid.New()
+
+	tests := []struct {
+		name    string
+		userID  func(target uuid.UUID) uuid.UUID // the user whose roles are set
+		current []string
+		body    string
+		want    int
+		revoked bool // whether the sessions of the user were ended
+	}{
+		{"promote", func(target uuid.UUID) uuid.UUID { return target }, []string{"user"}, `{"roles": ["moderator"]}`, http.StatusOK, false},
+		{"keep roles", func(target uuid.UUID) uuid.UUID { return target }, []string{"user", "moderator"}, `{"roles": ["moderator", "moderator"]}`, http.StatusOK, false},
+		{"demote", func(target uuid.UUID) uuid.UUID { return target }, []string{"user", "moderator"}, `{"roles": []}`, http.StatusOK, true},
+		{"swap roles", func(target uuid.UUID) uuid.UUID { return target }, []string{"user", "moderator"}, `{"roles": ["admin"]}`, http.StatusOK, true},
+		{"unknown role", func(target uuid.UUID) uuid.UUID { return target }, []string{"user"}, `{"roles": ["owner"]}`, http.StatusUnprocessableEntity, false},
+		{"unknown user", func(uuid.UUID) uuid.UUID { return uuid.New() }, []string{"user"}, `{"roles": ["moderator"]}`, http.StatusNotFound, false},
+		{"admin drops own admin role", func(uuid.UUID) uuid.UUID { return admin }, []string{"user", "admin"}, `{"roles": []}`, http.StatusUnprocessableEntity, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			posts := repository.NewMemoryPostRepository()
+			profiles := repository.NewMemoryProfileRepository(repository.NewMemorySocialRepository(posts))
+			tokens := auth.NewTokenService([]byte("test-secret"), "feast-friends-test", time.Minute, time.Hour, repository.NewMemorySessionRepository())
+			h := NewRoleHandler(profiles, tokens)
+
+			target := uuid.New()
+			for _, id := range []uuid.UUID{admin, target} {
+				if err := profiles.Create(ctx, &models.User{ID: id, Username: "user" + id.String()[:8]}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := profiles.SetRoles(ctx, admin, []string{"user", "admin"}); err != nil {
+				t.Fatal(err)
+			}
+			userID := tt.userID(target)
+			if userID == target {
+				if err := profiles.SetRoles(ctx, target, tt.current); err != nil {
+					t.Fatal(err)
+				}
+			}
+			session, err := tokens.Issue(ctx, userID, tt.current)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rec := serve(t, "PUT /users/{id}/roles", h.Set, http.MethodPut, "/users/"+userID.String()+"/roles", admin, tt.body)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+
+			_, err = tokens.Refresh(ctx, session.RefreshToken)
+			if revoked := errors.Is(err, auth.ErrTokenRevoked); revoked != tt.revoked {
+				t.Errorf("session revoked = %v (refresh error %v), want %v", revoked, err, tt.revoked)
+			}
+		})
+	}
+}
+
+func TestRoleHandlerSetRequiresAUser(t *testing.T) {
+	posts := repository.NewMemoryPostRepository()
+	h := NewRoleHandler(repository.NewMemoryProfileRepository(repository.NewMemorySocialRepository(posts)), nil)
+
+	rec := serve(t, "PUT /users/{id}/roles", h.Set, http.MethodPut, "/users/"+uuid.NewString()+"/roles", uuid.Nil, `{"roles": []}`)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestRemovedRoleLosesAccessRightAway(t *testing.T) {
+	ctx := context.Background()
+	posts := repository.NewMemoryPostRepository()
+	profiles := repository.NewMemoryProfileRepository(repository.NewMemorySocialRepository(posts))
+	tokens := auth.NewTokenService([]byte("test-secret"), "feast-friends-test", time.Hour, 24*time.Hour, repository.NewMemorySessionRepository())
+	tokens.UseRoles(profiles)
+	middleware.UseTokenService(tokens)
+	t.Cleanup(func() { middleware.UseTokenService(nil) })
+
+	admin, demoted := uuid.New(), uuid.New()
+	for _, id := range []uuid.UUID{admin, demoted} {
+		if err := profiles.Create(ctx, &models.User{ID: id, Username: "user" + id.String()[:8]}); err != nil {
+			t.Fatal(err)
+		}
+		if err := profiles.SetRoles(ctx, id, []string{auth.RoleUser, auth.RoleAdmin}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	session, err := tokens.Issue(ctx, demoted, []string{auth.RoleUser, auth.RoleAdmin})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := NewRoleHandler(profiles, tokens)
+	mux := http.NewServeMux()
+	mux.Handle("GET /users/{id}/roles", middleware.AuthMiddleware(middleware.RequireRole(auth.RoleAdmin)(http.HandlerFunc(h.Get))))
+	getRoles := func() int {
+		req := httptest.NewRequest(http.MethodGet, "/users/"+admin.String()+"/roles", nil)
+		req.Header.Set("Authorization", "Bearer "+session.AccessToken)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := getRoles(); code != http.StatusOK {
+		t.Fatalf("status as admin = %d, want %d", code, http.StatusOK)
+	}
+
+	rec := serve(t, "PUT /users/{id}/roles", h.Set, http.MethodPut, "/users/"+demoted.String()+"/roles", admin, `{"roles": []}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("removing the admin role: status = %d: %s", rec.Code, rec.Body)
+	}
+
+	// the access token still claims admin until it expires, the stored roles win
+	if code := getRoles(); code != http.StatusForbidden {
+		t.Errorf("status after the admin role was removed = %d, want %d", code, http.StatusForbidden)
+	}
+}
@@ -0,0 +1,181 @@
+// social.go contains the like/unlike and follow/unfollow handlers and the follower lists
+// like and follow endpoints are idempotent so clients can safely retry them
+
+package handlers
+
+import (
+	"errors"
+	"feast-friends-api/internal/repository"
+	"feast-friends-api/internal/utils"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// errSelfFollow is returned when a user tries to follow themself
+var errSelfFollow = errors.New("users cannot follow themselves")
+
+// SocialHandler serves the likes and follows endpoints
+type SocialHandler struct {
+	social repository.SocialRepository
+	posts  repository.PostRepository
+}
+
+// NewSocialHandler creates the handler, posts is used to return the updated like count
+func NewSocialHandler(social repository.SocialRepository, posts repository.PostRepository) *SocialHandler {
+	return &SocialHandler{social: social, posts: posts}
+}
+
+// likeResponse is returned by the like and unlike endpoints
+type likeResponse struct {
+	PostID     uuid.UUID `json:"post_id"`
+	Liked      bool      `json:"liked"`
+	LikesCount int       `json:"likes_count"`
+}
+
+// followResponse is returned by the follow and unfollow endpoints
+type followResponse struct {
+	UserID    uuid.UUID `json:"user_id"`
+	Following bool      `json:"following"`
+}
+
+// Like handles POST /posts/{id}/like
+func (h *SocialHandler) Like(w http.ResponseWriter, r *http.Request) {
+	h.setLike(w, r, true)
+}
+
+// Unlike handles DELETE /posts/{id}/like
+func (h *SocialHandler) Unlike(w http.ResponseWriter, r *http.Request) {
+	h.setLike(w, r, false)
+}
+
+// setLike likes or unlikes the post in the path and returns the new like count
+func (h *SocialHandler) setLike(w http.ResponseWriter, r *http.Request, liked bool) {
+	userID, err := currentUserID(r)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "Authentication required", err)
+		return
+	}
+	postID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid post id", err)
+		return
+	}
+
+	if liked {
+		err = h.social.Like(r.Context(), userID, postID)
+	} else {
+		err = h.social.Unlike(r.Context(), userID, postID)
+	}
+	if errors.Is(err, repository.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "Post not found", err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to update like", err)
+		return
+	}
+
+	post, err := h.posts.GetByID(r.Context(), postID)
+	if errors.Is(err, repository.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "Post not found", err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to load post", err)
+		return
+	}
+
+	message := "post liked"
+	if !liked {
+		message = "post unliked"
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.SuccessResponse(likeResponse{PostID: postID, Liked: liked, LikesCount: post.LikesCount}, message))
+}
+
+// Follow handles POST /users/{id}/follow
+func (h *SocialHandler) Follow(w http.ResponseWriter, r *http.Request) {
+	h.setFollow(w, r, true)
+}
+
+// Unfollow handles DELETE /users/{id}/follow
+func (h *SocialHandler) Unfollow(w http.ResponseWriter, r *http.Request) {
+	h.setFollow(w, r, false)
+}
+
+// setFollow follows or unfollows the user in the path
+func (h *SocialHandler) setFollow(w http.ResponseWriter, r *http.Request, follow bool) {
+	userID, err := currentUserID(r)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "Authentication required", err)
+		return
+	}
+	targetID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid user id", err)
+		return
+	}
+	if userID == targetID {
+		writeError(w, http.StatusBadRequest, "You cannot follow yourself", errSelfFollow)
+		return
+	}
+
+	if follow {
+		err = h.social.Follow(r.Context(), userID, targetID)
+	} else {
+		err = h.social.Unfollow(r.Context(), userID, targetID)
+	}
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		writeError(w, http.StatusNotFound, "User not found", err)
+		return
+	case errors.Is(err, repository.ErrInvalidRelation):
+		writeError(w, http.StatusBadRequest, "You cannot follow yourself", err)
+		return
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, "Failed to update follow", err)
+		return
+	}
+
+	message := "user followed"
+	if !follow {
+		message = "user unfollowed"
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.SuccessResponse(followResponse{UserID: targetID, Following: follow}, message))
+}
+
+// Followers handles GET /users/{id}/followers
+func (h *SocialHandler) Followers(w http.ResponseWriter, r *http.Request) {
+	userID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid user id", err)
+		return
+	}
+	page, limit, offset := pagination(r)
+
+	users, total, err := h.social.ListFollowers(r.Context(), userID, limit, offset)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to load followers", err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.PaginatedResponse("followers found", users, total, page, limit))
+}
+
+// Following handles GET /users/{id}/following
+func (h *SocialHandler) Following(w http.ResponseWriter, r *http.Request) {
+	userID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid user id", err)
+		return
+	}
+	page, limit, offset := pagination(r)
+
+	users, total, err := h.social.ListFollowing(r.Context(), userID, limit, offset)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to load following", err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.PaginatedResponse("following found", users, total, page, limit))
+}
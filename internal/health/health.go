@@ -0,0 +1,128 @@
+// Package health runs the dependency checks behind the readiness probe.
+// Results are cached for a short time and concurrent probes share one run, so a load balancer
+// probing every replica every second cannot turn into a ping storm against the database.
+package health
+
+import (
+	"context"
+	"feast-friends-api/pkg/logger"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// statuses reported for the whole report and for each check
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// Check is one dependency the api needs to serve requests
+type Check struct {
+	Name string
+	// Run returns nil when the dependency is usable, it must give up when ctx is done
+	Run func(ctx context.Context) error
+}
+
+// Result is the outcome of one check, the error is only logged since the probes are public
+type Result struct {
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+}
+
+// Report is the outcome of all the checks, Status is ok only when every check passed
+type Report struct {
+	Status    string            `json:"status"`
+	Checks    map[string]Result `json:"checks"`
+	CheckedAt time.Time         `json:"checked_at"`
+	// Draining is set once Drain was called, the checks are then not run
+	Draining bool `json:"draining,omitempty"`
+}
+
+// OK reports whether the server is ready to take traffic
+func (r Report) OK() bool {
+	return r.Status == StatusOK
+}
+
+// Checker runs the checks with a timeout and caches the report
+type Checker struct {
+	checks  []Check
+	timeout time.Duration
+	ttl     time.Duration
+
+	draining atomic.Bool
+
+	// mu is held while the checks run so concurrent probes wait for the same report
+	mu     sync.Mutex
+	report Report
+}
+
+// NewChecker returns a checker that gives each check timeout to answer and reuses a report for ttl
+func NewChecker(timeout, ttl time.Duration, checks ...Check) *Checker {
+	return &Checker{checks: checks, timeout: timeout, ttl: ttl}
+}
+
+// Drain makes every following report fail, it is called when graceful shutdown starts
+// so load balancers stop sending new requests while the in flight ones finish
+func (c *Checker) Drain() {
+	c.draining.Store(true)
+}
+
+// Report returns the cached report or runs the checks when it is older than the ttl
+func (c *Checker) Report(ctx context.Context) Report {
+	if c.draining.Load() {
+		return Report{Status: StatusFail, Checks: map[string]Result{}, CheckedAt: time.Now(), Draining: true}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.report.CheckedAt.IsZero() && time.Since(c.report.CheckedAt) < c.ttl {
+		return c.report
+	}
+
+	// the checks are not cut short by the probe that triggered them, the report is shared
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.timeout)
+	defer cancel()
+
+	results := make([]Result, len(c.checks))
+	var wg sync.WaitGroup
+	for i, check := range c.checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			results[i] = run(ctx, check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]Result, len(c.checks)), CheckedAt: time.Now()}
+	for i, check := range c.checks {
+		report.Checks[check.Name] = results[i]
+		if results[i].Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+	c.report = report
+	return report
+}
+
+// run runs one check and stops waiting for it once ctx is done, even when Run ignores ctx
+func run(ctx context.Context, check Check) Result {
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() { done <- check.Run(ctx) }()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result := Result{Status: StatusOK, LatencyMS: float64(time.Since(start).Microseconds()) / 1000}
+	if err != nil {
+		result.Status = StatusFail
+		logger.Warn("readiness check %s failed: %v", check.Name, err)
+	}
+	return result
+}
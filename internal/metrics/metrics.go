@@ -0,0 +1,93 @@
+// Package metrics holds the Prometheus collectors of the api and serves them on /metrics.
+// http metrics are recorded by middleware.Logs, the route label is the ServeMux pattern
+// (e.g. "GET /api/v1/posts/{id}") so ids in the path do not create a series per resource.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// UnmatchedRoute is the route label of requests no pattern matched (404s, scans)
+const UnmatchedRoute = "unmatched"
+
+// registry only holds our collectors, the default registry is left alone
+var registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests served, by method, route pattern and status code.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Time spent serving HTTP requests, by method, route pattern and status code.",
+		Buckets: []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+	}, []string{"method", "route", "status"})
+
+	httpResponseSize = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_response_size_bytes",
+		Help:    "Size of the HTTP response bodies, by method and route pattern.",
+		Buckets: prometheus.ExponentialBuckets(100, 10, 6), // 100B to 10MB
+	}, []string{"method", "route"})
+
+	httpInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "http_requests_in_flight",
+		Help: "HTTP requests currently being served.",
+	})
+)
+
+func init() {
+	registry.MustRegister(
+		httpRequests,
+		httpDuration,
+		httpResponseSize,
+		httpInFlight,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// knownMethods keeps the method label bounded, anything else is counted as "other"
+var knownMethods = map[string]bool{
+	http.MethodGet: true, http.MethodHead: true, http.MethodPost: true, http.MethodPut: true,
+	http.MethodPatch: true, http.MethodDelete: true, http.MethodOptions: true,
+}
+
+// ObserveRequest records one served request, route is the matched pattern or "" when none matched
+func ObserveRequest(method, route string, status int, duration time.Duration, bytes int64) {
+	if !knownMethods[method] {
+		method = "other"
+	}
+	if route == "" {
+		route = UnmatchedRoute
+	}
+	code := strconv.Itoa(status)
+
+	httpRequests.WithLabelValues(method, route, code).Inc()
+	httpDuration.WithLabelValues(method, route, code).Observe(duration.Seconds())
+	httpResponseSize.WithLabelValues(method, route).Observe(float64(bytes))
+}
+
+// RequestStarted counts the request as in flight, call the returned func when it is done
+func RequestStarted() func() {
+	httpInFlight.Inc()
+	return httpInFlight.Dec
+}
+
+// RegisterPool exposes the stats of the connection pool, it is called once at startup
+func RegisterPool(pool *pgxpool.Pool) {
+	registry.MustRegister(newPoolCollector(pool))
+}
+
+// Handler serves the metrics in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
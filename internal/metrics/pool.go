@@ -0,0 +1,73 @@
+// pool.go exposes pgxpool.Stat() as Prometheus metrics, the stats are read on every scrape
+
+package metrics
+
+import (
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// poolCollector reads the pool stats when Prometheus scrapes
+type poolCollector struct {
+	pool *pgxpool.Pool
+
+	acquired        *prometheus.Desc
+	idle            *prometheus.Desc
+	constructing    *prometheus.Desc
+	total           *prometheus.Desc
+	max             *prometheus.Desc
+	acquireCount    *prometheus.Desc
+	acquireDuration *prometheus.Desc
+	canceledAcquire *prometheus.Desc
+	emptyAcquire    *prometheus.Desc
+}
+
+// newPoolCollector creates the collector for the pool
+func newPoolCollector(pool *pgxpool.Pool) *poolCollector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc("pgxpool_"+name, help, nil, nil)
+	}
+	return &poolCollector{
+		pool:            pool,
+		acquired:        desc("acquired_connections", "Connections currently acquired by the application."),
+		idle:            desc("idle_connections", "Idle connections in the pool."),
+		constructing:    desc("constructing_connections", "Connections being established."),
+		total:           desc("total_connections", "Connections in the pool, acquired, idle and constructing."),
+		max:             desc("max_connections", "Maximum size of the pool."),
+		acquireCount:    desc("acquire_total", "Successful connection acquisitions."),
+		acquireDuration: desc("acquire_duration_seconds_total", "Total time spent waiting for a connection."),
+		canceledAcquire: desc("canceled_acquire_total", "Acquisitions canceled by their context."),
+		emptyAcquire:    desc("empty_acquire_total", "Acquisitions that had to wait because the pool was empty."),
+	}
+}
+
+// Describe sends the descriptions of the metrics
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{
+		c.acquired, c.idle, c.constructing, c.total, c.max,
+		c.acquireCount, c.acquireDuration, c.canceledAcquire, c.emptyAcquire,
+	} {
+		ch <- d
+	}
+}
+
+// Collect reads the current stats
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := c.pool.Stat()
+	gauge := func(d *prometheus.Desc, v float64) {
+		ch <- prometheus.MustNewConstMetric(d, prometheus.GaugeValue, v)
+	}
+	counter := func(d *prometheus.Desc, v float64) {
+		ch <- prometheus.MustNewConstMetric(d, prometheus.CounterValue, v)
+	}
+
+	gauge(c.acquired, float64(stat.AcquiredConns()))
+	gauge(c.idle, float64(stat.IdleConns()))
+	gauge(c.constructing, float64(stat.ConstructingConns()))
+	gauge(c.total, float64(stat.TotalConns()))
+	gauge(c.max, float64(stat.MaxConns()))
+	counter(c.acquireCount, float64(stat.AcquireCount()))
+	counter(c.acquireDuration, stat.AcquireDuration().Seconds())
+	counter(c.canceledAcquire, float64(stat.CanceledAcquireCount()))
+	counter(c.emptyAcquire, float64(stat.EmptyAcquireCount()))
+}
@@ -5,8 +5,13 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"feast-friends-api/internal/auth"
 	"feast-friends-api/internal/utils" // Assumes your utils package is in pkg/utils
+	"feast-friends-api/internal/repository"
 	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
 )
 
 // contextKey is a custom type for our context key. It's a Go best practice
@@ -14,8 +19,49 @@ import (
 type contextKey string
 
 // UserIDKey is the key we'll use to store and retrieve the user ID in the request context.
+// The value is the id as a string, handlers read it with UserIDFromContext.
 const UserIDKey contextKey = "userID"
 
+// RolesKey is the key of the roles ([]string) of the authenticated user, read them with RolesFromContext.
+const RolesKey contextKey = "roles"
+
+// UserIDFromContext returns the id of the user authenticated by AuthMiddleware.
+// ok is false when the request did not go through AuthMiddleware.
+func UserIDFromContext(ctx context.Context) (id uuid.UUID, ok bool) {
+	raw, ok := ctx.Value(UserIDKey).(string)
+	if !ok {
+		return uuid.Nil, false
+	}
+	id, err := uuid.Parse(raw)
+	return id, err == nil
+}
+
+// RolesFromContext returns the roles of the user authenticated by AuthMiddleware, nil without a user.
+func RolesFromContext(ctx context.Context) []string {
+	roles, _ := ctx.Value(RolesKey).([]string)
+	return roles
+}
+
+// HasRole reports whether the authenticated user has the role.
+func HasRole(ctx context.Context, role string) bool {
+	for _, r := range RolesFromContext(ctx) {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// sessionTokens verifies the tokens issued by the api and holds the revocation list.
+// It is nil until UseTokenService is called, AuthMiddleware then only accepts supabase tokens.
+var sessionTokens *auth.TokenService
+
+// UseTokenService makes AuthMiddleware accept the session tokens of the api and check revocations.
+// It is called once at startup before the server accepts requests.
+func UseTokenService(tokens *auth.TokenService) {
+	sessionTokens = tokens
+}
+
 // AuthMiddleware protects routes by verifying the JWT token from the Authorization header.
 func AuthMiddleware(next http.Handler) http.Handler {
 	// http.HandlerFunc is an adapter that allows the use of ordinary functions as HTTP handlers.
@@ -33,8 +79,8 @@ func AuthMiddleware(next http.Handler) http.Handler {
 			return // Terminate the request handling here.
 		}
 
-		// This function handles token verification against the auth provider.
-		userID, err := utils.ValidateToken(authHeader)
+		// This function handles token verification against the auth provider or our token service.
+		userID, roles, err := authenticate(r.Context(), authHeader)
 
 		// If the token is invalid (e.g., expired, wrong signature), an error will be returned.
 		if err != nil {
@@ -46,12 +92,96 @@ func AuthMiddleware(next http.Handler) http.Handler {
 		}
 
 		// At this point, the user is authenticated.
-		// We enrich the request's context with the validated userID.
-		// This makes the userID available to subsequent handlers in the chain.
+		// We enrich the request's context with the validated userID and its roles.
+		// This makes them available to subsequent handlers in the chain.
 		ctx := context.WithValue(r.Context(), UserIDKey, userID)
+		ctx = context.WithValue(ctx, RolesKey, roles)
+		recordUser(ctx, userID)
 
 		// The request is valid, so we pass it along to the next handler,
 		// complete with the updated context.
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
-}
\ No newline at end of file
+}
+
+// OptionalAuth is AuthMiddleware for public routes that personalise their response (e.g. "liked by me").
+// Requests without an Authorization header go through anonymously, a valid token sets UserIDKey and
+// RolesKey like AuthMiddleware does, and a malformed, expired or revoked token is still rejected with
+// a 401 so clients notice their session ended instead of silently browsing logged out.
+func OptionalAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authHeader := r.Header.Get("Authorization")
+		if authHeader == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		userID, roles, err := authenticate(r.Context(), authHeader)
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			response := utils.ErrorResponse("Invalid or expired token", err, http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), UserIDKey, userID)
+		ctx = context.WithValue(ctx, RolesKey, roles)
+		recordUser(ctx, userID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// authenticate returns the user id and roles of the bearer token. Tokens issued by the api are verified
+// by the token service and carry the roles, anything else is verified by utils.ValidateToken and gets
+// the roles stored on the profile. Both must not be on the revocation list.
+func authenticate(ctx context.Context, authHeader string) (string, []string, error) {
+	token := strings.TrimPrefix(authHeader, "Bearer ")
+	if sessionTokens == nil {
+		userID, err := utils.ValidateToken(token)
+		return userID, nil, err
+	}
+
+	if sessionTokens.Issued(token) {
+		claims, err := sessionTokens.VerifyAccess(ctx, token)
+		if err != nil {
+			return "", nil, err
+		}
+		return claims.Subject, claims.Roles, nil
+	}
+
+	userID, err := utils.ValidateToken(token)
+	if err != nil {
+		return "", nil, err
+	}
+	revoked, err := sessionTokens.IsRevoked(ctx, token)
+	if err != nil {
+		return "", nil, err
+	}
+	if revoked {
+		return "", nil, auth.ErrTokenRevoked
+	}
+
+	// ValidateToken only accepts uuid subjects
+	roles, err := sessionTokens.Roles(ctx, uuid.MustParse(userID))
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return "", nil, err
+	}
+	return userID, roles, nil
+}
+
+// AccessTokenParam is the query parameter TokenFromQuery reads the token from.
+const AccessTokenParam = "access_token"
+
+// TokenFromQuery copies ?access_token= into the Authorization header when the header is missing.
+// Browsers cannot set headers on WebSocket or EventSource requests, so the realtime stream passes
+// the token in the url and is then checked by AuthMiddleware like every other protected route.
+func TokenFromQuery(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token := r.URL.Query().Get(AccessTokenParam); token != "" && r.Header.Get("Authorization") == "" {
+			r = r.Clone(r.Context())
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	if err := UseTrustedProxies([]string{"10.0.0.0/8", " 192.168.1.1 ", "", "fd00::/8"}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { UseTrustedProxies(nil) })
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string
+		realIP     string
+		want       string
+	}{
+		{"direct client", "203.0.113.7:5000", nil, "", "203.0.113.7"},
+		{"untrusted peer cannot forward", "203.0.113.7:5000", []string{"198.51.100.1"}, "198.51.100.2", "203.0.113.7"},
+		{"trusted proxy", "10.0.0.2:5000", []string{"198.51.100.1"}, "", "198.51.100.1"},
+		{"trusted single ip", "192.168.1.1:5000", []string{"198.51.100.1"}, "", "198.51.100.1"},
+		{"forged left hops are skipped", "10.0.0.2:5000", []string{"1.1.1.1, 198.51.100.1, 10.0.0.3"}, "", "198.51.100.1"},
+		{"several headers", "10.0.0.2:5000", []string{"1.1.1.1", "198.51.100.1"}, "", "198.51.100.1"},
+		{"only proxies", "10.0.0.2:5000", []string{"10.0.0.4, 10.0.0.3"}, "", "10.0.0.4"},
+		{"malformed hop", "10.0.0.2:5000", []string{"198.51.100.1, nonsense, 10.0.0.3"}, "", "10.0.0.3"},
+		{"real ip", "10.0.0.2:5000", nil, "198.51.100.1", "198.51.100.1"},
+		{"malformed real ip", "10.0.0.2:5000", nil, "nonsense", "10.0.0.2"},
+		{"ipv6 proxy", "[fd00::1]:5000", []string{"2001:db8::1"}, "", "2001:db8::1"},
+		{"ipv4 mapped proxy", "[::ffff:10.0.0.2]:5000", []string{"198.51.100.1"}, "", "198.51.100.1"},
+		{"no port", "203.0.113.7", nil, "", "203.0.113.7"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.forwarded {
+				req.Header.Add("X-Forwarded-For", value)
+			}
+			if tt.realIP != "" {
+				req.Header.Set("X-Real-IP", tt.realIP)
+			}
+			if got := clientIP(req); got != tt.want {
+				t.Errorf("clientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUseTrustedProxiesRejectsInvalidEntries(t *testing.T) {
+	t.Cleanup(func() { UseTrustedProxies(nil) })
+	for _, proxy := range []string{"10.0.0", "10.0.0.0/33", "proxy.internal"} {
+		if err := UseTrustedProxies([]string{proxy}); err == nil {
+			t.Errorf("UseTrustedProxies(%q) accepted an invalid entry", proxy)
+		}
+	}
+}
@@ -1,15 +1,23 @@
-//cors.go this file tells the server which frontend is allowed to make requests to it 
+//cors.go this file tells the server which frontends are allowed to make requests to it 
 //allows specific http methods,headers and cookies can be send 
 //handles preflight options incase the server wants to know what is can request 
+//CORS is configured with a CORSPolicy, CROS is the old single origin version kept for compatibility
 
 
 package middleware
 import (
+	"errors"
 	"net/http"
 	"feast-friends-api/internal/config"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // CROS adds CORS headers to HTTP responses.
+// It always allows the single FRONTEND_URL origin with fixed methods and headers.
+//
+// Deprecated: use CORS, which checks the request origin against a list.
 func CROS(next http.Handler) http.Handler {
 	Origin := config.Get().Server.Frontend
 
@@ -39,4 +47,138 @@ func CROS(next http.Handler) http.Handler {
 		// Call the next handler
 		next.ServeHTTP(w, r)
 	})
-}
\ No newline at end of file
+}
+
+// CORSPolicy says which cross origin requests CORS allows.
+type CORSPolicy struct {
+	// AllowedOrigins are exact origins ("https://feastfriends.app"), wildcard subdomains
+	// ("https://*.feastfriends.app") or "*" for any origin
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string // "*" allows whatever headers the preflight asks for
+	ExposedHeaders   []string
+	AllowCredentials bool
+	MaxAge           time.Duration // how long browsers may cache a preflight, 0 leaves it to the browser
+}
+
+// CORSPolicyFromConfig builds the policy from the CORS_* variables.
+func CORSPolicyFromConfig(cfg *config.Config) CORSPolicy {
+	return CORSPolicy{
+		AllowedOrigins:   cfg.CORS.AllowedOrigins,
+		AllowedMethods:   cfg.CORS.AllowedMethods,
+		AllowedHeaders:   cfg.CORS.AllowedHeaders,
+		ExposedHeaders:   cfg.CORS.ExposedHeaders,
+		AllowCredentials: cfg.CORS.AllowCredentials,
+		MaxAge:           cfg.CORS.MaxAge,
+	}
+}
+
+// errWildcardCredentials is returned by Validate for "*" with credentials, browsers refuse that combination
+// and echoing every origin instead would let any site make credentialed requests
+var errWildcardCredentials = errors.New(`CORS_ALLOWED_ORIGINS "*" cannot be used with CORS_ALLOW_CREDENTIALS=true, list the origins or turn credentials off`)
+
+// Validate rejects policies that would open the api to every site with credentials.
+func (p CORSPolicy) Validate() error {
+	if p.AllowCredentials && p.allowsAny() {
+		return errWildcardCredentials
+	}
+	return nil
+}
+
+// allowsAny reports whether "*" is one of the allowed origins
+func (p CORSPolicy) allowsAny() bool {
+	for _, allowed := range p.AllowedOrigins {
+		if strings.TrimSpace(allowed) == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsOrigin reports whether the origin matches one of the allowed origins, ignoring case.
+func (p CORSPolicy) AllowsOrigin(origin string) bool {
+	origin = strings.ToLower(strings.TrimSpace(origin))
+	if origin == "" {
+		return false
+	}
+	for _, allowed := range p.AllowedOrigins {
+		allowed = strings.ToLower(strings.TrimRight(strings.TrimSpace(allowed), "/"))
+		if allowed == "*" || allowed == origin {
+			return true
+		}
+		// "https://*.example.com" matches "https://a.example.com" and "https://a.b.example.com"
+		if scheme, domain, ok := strings.Cut(allowed, "://*."); ok {
+			host, found := strings.CutPrefix(origin, scheme+"://")
+			if found && strings.HasSuffix(host, "."+domain) && !strings.ContainsAny(host, "/@") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// CORS answers preflights and adds the CORS headers for the origins the policy allows.
+// The allowed origin is echoed back so credentials keep working, with Vary: Origin so caches keep the
+// answers apart. A policy allowing "*" answers with a literal "*" and never allows credentials. Preflights from other origins get a 403, their other requests go through
+// without CORS headers and the browser keeps the response from the page.
+func CORS(policy CORSPolicy) func(http.Handler) http.Handler {
+	methods := strings.Join(policy.AllowedMethods, ", ")
+	headers := strings.Join(policy.AllowedHeaders, ", ")
+	exposed := strings.Join(policy.ExposedHeaders, ", ")
+	maxAge := strconv.Itoa(int(policy.MaxAge.Seconds()))
+	anyHeader := len(policy.AllowedHeaders) == 1 && policy.AllowedHeaders[0] == "*"
+	anyOrigin := policy.allowsAny()
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Origin")
+			origin := r.Header.Get("Origin")
+			preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+			// same origin requests and non browser clients
+			if origin == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if !policy.AllowsOrigin(origin) {
+				if preflight {
+					w.WriteHeader(http.StatusForbidden)
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if anyOrigin {
+				w.Header().Set("Access-Control-Allow-Origin", "*")
+			} else {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+			}
+			if policy.AllowCredentials && !anyOrigin {
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+			}
+
+			if !preflight {
+				if exposed != "" {
+					w.Header().Set("Access-Control-Expose-Headers", exposed)
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Add("Vary", "Access-Control-Request-Method")
+			w.Header().Add("Vary", "Access-Control-Request-Headers")
+			w.Header().Set("Access-Control-Allow-Methods", methods)
+			if anyHeader {
+				w.Header().Set("Access-Control-Allow-Headers", r.Header.Get("Access-Control-Request-Headers"))
+			} else if headers != "" {
+				w.Header().Set("Access-Control-Allow-Headers", headers)
+			}
+			if policy.MaxAge > 0 {
+				w.Header().Set("Access-Control-Max-Age", maxAge)
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCORSPolicyAllowsOrigin(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed []string
+		origin  string
+		want    bool
+	}{
+		{"exact", []string{"https://feastfriends.app"}, "https://feastfriends.app", true},
+		{"exact ignores case", []string{"https://FeastFriends.app"}, "https://feastfriends.APP", true},
+		{"exact with trailing slash", []string{"https://feastfriends.app/"}, "https://feastfriends.app", true},
+		{"other origin", []string{"https://feastfriends.app"}, "https://evil.app", false},
+		{"other scheme", []string{"https://feastfriends.app"}, "http://feastfriends.app", false},
+		{"other port", []string{"https://feastfriends.app"}, "https://feastfriends.app:8443", false},
+		{"empty origin", []string{"*"}, "", false},
+		{"any", []string{"*"}, "https://evil.app", true},
+		{"subdomain", []string{"https://*.feastfriends.app"}, "https://staging.feastfriends.app", true},
+		{"nested subdomain", []string{"https://*.feastfriends.app"}, "https://a.b.feastfriends.app", true},
+		{"wildcard needs a subdomain", []string{"https://*.feastfriends.app"}, "https://feastfriends.app", false},
+		{"wildcard suffix only", []string{"https://*.feastfriends.app"}, "https://evilfeastfriends.app", false},
+		{"wildcard other domain", []string{"https://*.feastfriends.app"}, "https://feastfriends.app.evil.com", false},
+		{"wildcard other scheme", []string{"https://*.feastfriends.app"}, "http://staging.feastfriends.app", false},
+		{"wildcard userinfo", []string{"https://*.feastfriends.app"}, "https://evil.com@a.feastfriends.app", false},
+		{"wildcard path", []string{"https://*.feastfriends.app"}, "https://evil.com/.feastfriends.app", false},
+		{"second entry", []string{"https://feastfriends.app", "http://localhost:3000"}, "http://localhost:3000", true},
+		{"nothing allowed", nil, "https://feastfriends.app", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := CORSPolicy{AllowedOrigins: tt.allowed}
+			if got := policy.AllowsOrigin(tt.origin); got != tt.want {
+				t.Errorf("AllowsOrigin(%q) with %q = %v, want %v", tt.origin, tt.allowed, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCORSPolicyValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  CORSPolicy
+		wantErr bool
+	}{
+		{"origins with credentials", CORSPolicy{AllowedOrigins: []string{"https://feastfriends.app"}, AllowCredentials: true}, false},
+		{"any without credentials", CORSPolicy{AllowedOrigins: []string{"*"}}, false},
+		{"any with credentials", CORSPolicy{AllowedOrigins: []string{"https://feastfriends.app", " * "}, AllowCredentials: true}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.policy.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCORSHeaders(t *testing.T) {
+	tests := []struct {
+		name        string
+		policy      CORSPolicy
+		method      string
+		origin      string
+		status      int
+		allowOrigin string
+		credentials string
+	}{
+		{
+			name:        "allowed origin is echoed",
+			policy:      CORSPolicy{AllowedOrigins: []string{"https://*.feastfriends.app"}, AllowCredentials: true},
+			method:      http.MethodGet,
+			origin:      "https://staging.feastfriends.app",
+			status:      http.StatusOK,
+			allowOrigin: "https://staging.feastfriends.app",
+			credentials: "true",
+		},
+		{
+			name:        "any origin answers a literal star",
+			policy:      CORSPolicy{AllowedOrigins: []string{"*"}},
+			method:      http.MethodGet,
+			origin:      "https://evil.app",
+			status:      http.StatusOK,
+			allowOrigin: "*",
+		},
+		{
+			name:   "other origin gets no headers",
+			policy: CORSPolicy{AllowedOrigins: []string{"https://feastfriends.app"}, AllowCredentials: true},
+			method: http.MethodGet,
+			origin: "https://evil.app",
+			status: http.StatusOK,
+		},
+		{
+			name:        "preflight",
+			policy:      CORSPolicy{AllowedOrigins: []string{"https://feastfriends.app"}, AllowedMethods: []string{"GET", "POST"}},
+			method:      http.MethodOptions,
+			origin:      "https://feastfriends.app",
+			status:      http.StatusNoContent,
+			allowOrigin: "https://feastfriends.app",
+		},
+		{
+			name:   "preflight from other origin",
+			policy: CORSPolicy{AllowedOrigins: []string{"https://feastfriends.app"}},
+			method: http.MethodOptions,
+			origin: "https://evil.app",
+			status: http.StatusForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := CORS(tt.policy)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+			req := httptest.NewRequest(tt.method, "/posts", nil)
+			req.Header.Set("Origin", tt.origin)
+			if tt.method == http.MethodOptions {
+				req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+			}
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Origin"); got != tt.allowOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.allowOrigin)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Credentials"); got != tt.credentials {
+				t.Errorf("Access-Control-Allow-Credentials = %q, want %q", got, tt.credentials)
+			}
+		})
+	}
+}
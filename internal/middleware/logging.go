@@ -1,22 +1,35 @@
 // logging.go logs every http req and res that goes through the server 
 // it skips health chekpoints and formats logs depending if its in dev or production using logger pkg 
+// the request id is put in the request context so logger.*Context calls made while serving it log the id too
+// it also records the request count, latency and size for the prometheus metrics, labelled by route pattern
 
 
 package middleware
 
 import (
+	"bufio"
+	"context"
+	"errors"
+	"feast-friends-api/internal/metrics"
 	"feast-friends-api/pkg/logger"
+	"net"
 	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 
 )
 
-type responseWriter struct{ //this allows us to log the statues code 
+type responseWriter struct{ //this allows us to log the statues code and the size of the body
 	http.ResponseWriter
 
 	status int
+	bytes  int64
+	// wroteHeader is set once the status line went out, Recover can then no longer send its 500
+	wroteHeader bool
 }
  
 // WriteHeader overrides the default WriteHeader method of http.ResponseWriter.
@@ -24,38 +37,151 @@ type responseWriter struct{ //this allows us to log the statues code
 // This is necessary because the standard http.ResponseWriter does not expose the status code after writing.
 func (rw *responseWriter) WriteHeader(code int) {
 	rw.status = code // Store the status code for logging
+	rw.wroteHeader = true
 	rw.ResponseWriter.WriteHeader(code) // Call the underlying ResponseWriter's WriteHeader method
 }
 
+// Write counts the bytes of the body for the access log.
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	n, err := rw.ResponseWriter.Write(b)
+	rw.bytes += int64(n)
+	rw.wroteHeader = true
+	return n, err
+}
+
+// Flush lets streaming handlers (server sent events) push data through the wrapper.
+func (rw *responseWriter) Flush() {
+	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
+		rw.wroteHeader = true
+		f.Flush()
+	}
+}
+
+// Hijack lets the websocket upgrade take over the connection through the wrapper.
+// the status is recorded as 101 because the handler never calls WriteHeader itself
+func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := rw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("underlying response writer does not support hijacking")
+	}
+	rw.status = http.StatusSwitchingProtocols
+	rw.wroteHeader = true
+	return h.Hijack()
+}
+
+// Unwrap returns the original ResponseWriter so http.ResponseController can reach it.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// accessLogKey is the context key of the accessLog of the request
+const accessLogKey contextKey = "accessLog"
+
+// accessLog holds what handlers further down learn about the request, Logs writes it out at the end.
+// Logs runs before AuthMiddleware so the user id has to be handed back through this holder.
+type accessLog struct {
+	userID string
+	// route is the ServeMux pattern that matched, "" when none did
+	route string
+	// traceID is the id of the trace started by Tracing, "" when tracing is off
+	traceID string
+}
+
+// loggedUser returns the user recorded for the access log, "" when unknown.
+func loggedUser(ctx context.Context) string {
+	if entry, ok := ctx.Value(accessLogKey).(*accessLog); ok {
+		return entry.userID
+	}
+	return ""
+}
+
+// recordUser tells Logs which user made the request, it does nothing outside of Logs.
+func recordUser(ctx context.Context, userID string) {
+	if entry, ok := ctx.Value(accessLogKey).(*accessLog); ok {
+		entry.userID = userID
+	}
+}
+
+// Routes wraps the ServeMux so Logs knows which pattern served the request.
+// The mux sets r.Pattern on the request it receives, the middlewares between Logs and the mux pass
+// copies of the request along so the pattern is read here and handed back through the access log holder.
+func Routes(mux http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		entry, ok := r.Context().Value(accessLogKey).(*accessLog)
+		if !ok {
+			mux.ServeHTTP(w, r)
+			return
+		}
+		// deferred so requests that panic (and are answered by Recover) still get their route
+		defer func() { entry.route = r.Pattern }()
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// validRequestID limits the request ids accepted from clients so they cannot mess up the logs
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
 func Logs(next http.Handler) http.Handler { //returns a http handler that we can use in request
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Path == "/health" { //if the request is to /health we dont log it call neextServe straight waway 
+		if r.URL.Path == "/health" || strings.HasPrefix(r.URL.Path, "/health/") { //if the request is to /health (or a probe under it) we dont log it call neextServe straight waway 
 			next.ServeHTTP(w, r)
 			return
 		}
 
 		start := time.Now() //will record the current time 
+		defer metrics.RequestStarted()()
 
 		rw := &responseWriter{ResponseWriter: w, status: http.StatusOK} // custom wrapper o track http stat code because the standard response writer dosnt expose it after writing 
 
-		reqID := r.Header.Get("X-Request-ID") //checks if the client sent a request id . if not we generate one using uuid
-		if reqID == "" {
+		reqID := r.Header.Get("X-Request-ID") //checks if the client sent a request id . if not (or it looks wrong) we generate one using uuid
+		if !validRequestID.MatchString(reqID) {
 			reqID = uuid.NewString()
 		} 
 
 		rw.Header().Set("X-Request-ID",reqID) // adds the request id to the response header so client can see it 
 
-		duration := time.Since(start).Milliseconds() // tracks how long request took by subing start from current time 
+		// the request id goes in the context for the logger and the access log holder for AuthMiddleware
+		entry := &accessLog{}
+		ctx := logger.ContextWithRequestID(r.Context(), reqID)
+		ctx = context.WithValue(ctx, accessLogKey, entry)
+
+		next.ServeHTTP(rw, r.WithContext(ctx))
+
+		elapsed := time.Since(start)
+		duration := float64(elapsed.Microseconds()) / 1000 // how long the request took in milliseconds, measured once the handler is done
+		metrics.ObserveRequest(r.Method, entry.route, rw.status, elapsed, rw.bytes)
 
-		
-		next.ServeHTTP(rw, r)
-	
-		logger.Log.WithFields(map[string]interface{}{
+		fields := map[string]interface{}{
 			"method":    r.Method,
-			"url":       r.URL.String(),
+			"url":       redactedURL(r.URL),
 			"status":    rw.status,
 			"duration":  duration,
+			"bytes":     rw.bytes,
+			"ip":        clientIP(r),
+			"userAgent": r.UserAgent(),
 			"requestID": reqID,
-		}).Info("Http request completed")
+		}
+		if entry.userID != "" {
+			fields["userID"] = entry.userID
+		}
+		if entry.route != "" {
+			fields["route"] = entry.route
+		}
+		if entry.traceID != "" {
+			fields["traceID"] = entry.traceID
+		}
+		logger.Log.WithFields(fields).Info("Http request completed")
 	})
-}
\ No newline at end of file
+}
+
+// redactedURL returns the url for the logs with the access token query param hidden.
+func redactedURL(u *url.URL) string {
+	query := u.Query()
+	if !query.Has(AccessTokenParam) {
+		return u.String()
+	}
+	query.Set(AccessTokenParam, "REDACTED")
+	redacted := *u
+	redacted.RawQuery = query.Encode()
+	return redacted.String()
+}
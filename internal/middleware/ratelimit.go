@@ -0,0 +1,82 @@
+// ratelimit.go throttles requests with token buckets, one bucket per route group and client
+// clients are the authenticated user (UserIDKey) or the client ip for anonymous requests,
+// the buckets live in a RateLimitStore so several replicas can share them
+
+package middleware
+
+import (
+	"context"
+	"errors"
+	"feast-friends-api/internal/config"
+	"feast-friends-api/internal/utils"
+	"feast-friends-api/pkg/logger"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RateLimitResult is the state of a bucket after taking a token from it
+type RateLimitResult struct {
+	Allowed   bool
+	Remaining int           // whole tokens left in the bucket
+	Reset     time.Duration // until the bucket is full again
+	// RetryAfter is how long until the next token, zero when the request was allowed
+	RetryAfter time.Duration
+}
+
+// RateLimitStore keeps the token buckets, the in-memory store works for a single replica
+// and a shared store (e.g. redis or postgres) can be plugged in for several
+type RateLimitStore interface {
+	// Take removes one token from the bucket of key, creating a full bucket the first time
+	Take(ctx context.Context, key string, limit config.RateLimit) (RateLimitResult, error)
+}
+
+// RateLimit limits the requests of each client to the handler, name keeps the buckets of different
+// route groups apart. Put it after AuthMiddleware to limit by user, otherwise clients are limited by ip.
+// A nil store or a disabled limit lets every request through, a failing store too so an outage of a
+// shared store does not take the api down.
+func RateLimit(store RateLimitStore, name string, limit config.RateLimit) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if store == nil || !limit.Enabled() {
+			return next
+		}
+		policy := strconv.Itoa(limit.Requests) + ";w=" + strconv.Itoa(seconds(limit.Period))
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			result, err := store.Take(r.Context(), name+":"+rateLimitClient(r), limit)
+			if err != nil {
+				logger.ErrorContext(r.Context(), "rate limit store failed, request allowed: %v", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// headers from the IETF RateLimit header fields draft
+			w.Header().Set("RateLimit-Policy", policy)
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(limit.Requests))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			w.Header().Set("RateLimit-Reset", strconv.Itoa(seconds(result.Reset)))
+
+			if !result.Allowed {
+				w.Header().Set("Retry-After", strconv.Itoa(seconds(result.RetryAfter)))
+				utils.WriteJSON(w, http.StatusTooManyRequests, utils.ErrorResponse("Too many requests, slow down",
+					errors.New("rate limit "+name+" exceeded"), http.StatusTooManyRequests))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// rateLimitClient is the bucket owner, "user:<id>" after AuthMiddleware and "ip:<addr>" otherwise
+func rateLimitClient(r *http.Request) string {
+	if id, ok := UserIDFromContext(r.Context()); ok {
+		return "user:" + id.String()
+	}
+	return "ip:" + clientIP(r)
+}
+
+// seconds rounds up so clients never retry before the token is there
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,82 @@
+// ratelimit_memory.go is an in-memory RateLimitStore, buckets are not shared between replicas
+// idle buckets are dropped once they are full again since a new bucket starts full anyway
+
+package middleware
+
+import (
+	"context"
+	"feast-friends-api/internal/config"
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often Take looks for buckets to drop
+const sweepInterval = time.Minute
+
+// bucket is the state of one token bucket
+type bucket struct {
+	tokens float64
+	last   time.Time // when tokens was computed
+	full   time.Time // when the bucket is full again if nobody takes from it
+}
+
+// MemoryRateLimitStore keeps the buckets in a map
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// make sure the implementation satisfies the interface at compile time
+var _ RateLimitStore = (*MemoryRateLimitStore)(nil)
+
+// NewMemoryRateLimitStore creates an empty store
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{buckets: make(map[string]*bucket)}
+}
+
+// Take refills the bucket for the time since the last request and removes one token if there is one
+func (s *MemoryRateLimitStore) Take(ctx context.Context, key string, limit config.RateLimit) (RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.sweep(now)
+
+	capacity := float64(limit.Requests)
+	perToken := float64(limit.Period) / capacity // nanoseconds to refill one token
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, last: now}
+		s.buckets[key] = b
+	}
+	b.tokens = math.Min(capacity, b.tokens+float64(now.Sub(b.last))/perToken)
+	b.last = now
+
+	result := RateLimitResult{}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration((1 - b.tokens) * perToken)
+	}
+	result.Remaining = int(b.tokens)
+	result.Reset = time.Duration((capacity - b.tokens) * perToken)
+	b.full = now.Add(result.Reset)
+	return result, nil
+}
+
+// sweep drops the buckets that refilled completely, at most once per sweepInterval
+func (s *MemoryRateLimitStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for key, b := range s.buckets {
+		if !now.Before(b.full) {
+			delete(s.buckets, key)
+		}
+	}
+}
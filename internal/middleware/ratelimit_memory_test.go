@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"context"
+	"feast-friends-api/internal/config"
+	"testing"
+	"time"
+)
+
+func TestMemoryRateLimitStoreTake(t *testing.T) {
+	limit := config.RateLimit{Requests: 3, Period: time.Hour}
+	tests := []struct {
+		name      string
+		key       string
+		allowed   bool
+		remaining int
+	}{
+		{"first", "ip:a", true, 2},
+		{"second", "ip:a", true, 1},
+		{"third", "ip:a", true, 0},
+		{"bucket empty", "ip:a", false, 0},
+		{"other key has its own bucket", "ip:b", true, 2},
+		{"still empty", "ip:a", false, 0},
+	}
+
+	store := NewMemoryRateLimitStore()
+	for _, tt := range tests {
+		result, err := store.Take(context.Background(), tt.key, limit)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if result.Allowed != tt.allowed || result.Remaining != tt.remaining {
+			t.Errorf("%s: Take() = allowed %v remaining %d, want allowed %v remaining %d",
+				tt.name, result.Allowed, result.Remaining, tt.allowed, tt.remaining)
+		}
+		if result.Allowed && result.RetryAfter != 0 {
+			t.Errorf("%s: RetryAfter = %v on an allowed request", tt.name, result.RetryAfter)
+		}
+		if !result.Allowed {
+			// one token every 20 minutes
+			if result.RetryAfter <= 19*time.Minute || result.RetryAfter > 20*time.Minute {
+				t.Errorf("%s: RetryAfter = %v, want about 20m", tt.name, result.RetryAfter)
+			}
+			if result.Reset <= 59*time.Minute || result.Reset > time.Hour {
+				t.Errorf("%s: Reset = %v, want about 1h", tt.name, result.Reset)
+			}
+		}
+	}
+}
+
+func TestMemoryRateLimitStoreRefills(t *testing.T) {
+	limit := config.RateLimit{Requests: 2, Period: 100 * time.Millisecond}
+	store := NewMemoryRateLimitStore()
+	take := func() RateLimitResult {
+		result, err := store.Take(context.Background(), "ip:a", limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	take()
+	take()
+	if take().Allowed {
+		t.Fatal("request allowed on an empty bucket")
+	}
+	time.Sleep(60 * time.Millisecond)
+	if !take().Allowed {
+		t.Fatal("bucket did not refill a token")
+	}
+	time.Sleep(150 * time.Millisecond)
+	if result := take(); !result.Allowed || result.Remaining != 1 {
+		t.Fatalf("bucket refilled to more than its capacity: remaining %d after taking one", result.Remaining)
+	}
+}
@@ -0,0 +1,83 @@
+// recovery.go turns a panicking handler into a logged 500 instead of a dropped connection
+// the stack trace is logged with the request id and handed to a CrashReporter (an error tracker)
+
+package middleware
+
+import (
+	"context"
+	"errors"
+	"feast-friends-api/internal/utils"
+	"feast-friends-api/pkg/logger"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"time"
+)
+
+// Crash describes a recovered panic
+type Crash struct {
+	Time      time.Time `json:"time"`
+	Panic     string    `json:"panic"`
+	Stack     string    `json:"stack"`
+	RequestID string    `json:"request_id,omitempty"`
+	UserID    string    `json:"user_id,omitempty"`
+	Method    string    `json:"method"`
+	URL       string    `json:"url"`
+}
+
+// CrashReporter forwards crashes to an error tracker. Report runs on the request goroutine
+// after the panic, so implementations that call a remote service should not block for long.
+type CrashReporter interface {
+	Report(ctx context.Context, crash Crash) error
+}
+
+// Recover catches panics of the handlers below it, logs them and answers with the usual JSON 500.
+// reporter may be nil. Put it inside Logs so the request id is known and the 500 is logged.
+// http.ErrAbortHandler is passed on, it is how handlers abort a response on purpose.
+func Recover(reporter CrashReporter) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rw := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+			defer func() {
+				recovered := recover()
+				if recovered == nil {
+					return
+				}
+				if recovered == http.ErrAbortHandler {
+					panic(recovered)
+				}
+
+				crash := Crash{
+					Time:      time.Now().UTC(),
+					Panic:     fmt.Sprint(recovered),
+					Stack:     string(debug.Stack()),
+					RequestID: logger.RequestIDFromContext(r.Context()),
+					UserID:    loggedUser(r.Context()),
+					Method:    r.Method,
+					URL:       redactedURL(r.URL),
+				}
+				logger.FromContext(r.Context()).WithFields(map[string]interface{}{
+					"panic":  crash.Panic,
+					"stack":  crash.Stack,
+					"method": crash.Method,
+					"url":    crash.URL,
+				}).Error("panic recovered")
+
+				if reporter != nil {
+					if err := reporter.Report(context.WithoutCancel(r.Context()), crash); err != nil {
+						logger.ErrorContext(r.Context(), "failed to report crash: %v", err)
+					}
+				}
+
+				// once the status line is out the client gets a truncated response, nothing else can be sent
+				if rw.wroteHeader {
+					return
+				}
+				utils.WriteJSON(w, http.StatusInternalServerError, utils.ErrorResponse("Internal server error",
+					errors.New("panic: "+crash.Panic), http.StatusInternalServerError))
+			}()
+
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
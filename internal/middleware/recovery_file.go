@@ -0,0 +1,50 @@
+// recovery_file.go is a CrashReporter that appends crashes to a local file as json lines
+// it stands in for an error tracker in development and tests
+
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// FileCrashReporter writes one json object per crash
+type FileCrashReporter struct {
+	mu   sync.Mutex
+	path string
+}
+
+// make sure the implementation satisfies the interface at compile time
+var _ CrashReporter = (*FileCrashReporter)(nil)
+
+// NewFileCrashReporter creates the reporter, the file and its directory are created on the first crash
+func NewFileCrashReporter(path string) *FileCrashReporter {
+	return &FileCrashReporter{path: path}
+}
+
+// Report appends the crash to the file
+func (f *FileCrashReporter) Report(ctx context.Context, crash Crash) error {
+	line, err := json.Marshal(crash)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(f.path), 0o755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
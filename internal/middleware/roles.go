@@ -0,0 +1,61 @@
+// roles.go restricts routes to users with a role (admin, moderator), the roles come from AuthMiddleware
+// and are read again from the profile when a token service is set, tokens keep the roles they were issued with
+
+package middleware
+
+import (
+	"context"
+	"errors"
+	"feast-friends-api/internal/repository"
+	"feast-friends-api/internal/utils"
+	"net/http"
+	"strings"
+)
+
+// RequireRole only lets requests through when the user has at least one of the roles.
+// It reads the context set by AuthMiddleware so it must be composed after it:
+//
+//	middleware.AuthMiddleware(middleware.RequireRole(auth.RoleAdmin)(handler))
+//
+// The roles of the token are replaced with the stored ones when UseTokenService set a token service with a
+// RoleLookup, so a removed role stops working right away instead of when the access token expires.
+//
+// Requests without an authenticated user get a 401, users without the role a 403.
+func RequireRole(roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, ok := UserIDFromContext(r.Context())
+			if !ok {
+				utils.WriteJSON(w, http.StatusUnauthorized, utils.ErrorResponse("Authentication required",
+					errors.New("RequireRole used without AuthMiddleware"), http.StatusUnauthorized))
+				return
+			}
+
+			if sessionTokens != nil {
+				stored, err := sessionTokens.Roles(r.Context(), userID)
+				switch {
+				case errors.Is(err, repository.ErrNotFound):
+					stored = []string{}
+				case err != nil:
+					utils.WriteJSON(w, http.StatusInternalServerError, utils.ErrorResponse("Failed to check permissions",
+						err, http.StatusInternalServerError))
+					return
+				}
+				// nil when the token service has no RoleLookup, the token roles are all there is then
+				if stored != nil {
+					r = r.WithContext(context.WithValue(r.Context(), RolesKey, stored))
+				}
+			}
+
+			for _, role := range roles {
+				if HasRole(r.Context(), role) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			utils.WriteJSON(w, http.StatusForbidden, utils.ErrorResponse("Insufficient permissions",
+				errors.New("user lacks any of the roles "+strings.Join(roles, ", ")), http.StatusForbidden))
+		})
+	}
+}
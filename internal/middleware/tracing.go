@@ -0,0 +1,67 @@
+// tracing.go starts an OpenTelemetry span for every request that goes through the server
+// it continues the trace of an incoming traceparent header and puts the span in the request context
+// so the db helpers in utils can add child spans to it
+
+package middleware
+
+import (
+	"feast-friends-api/pkg/logger"
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestIDAttribute is the span attribute holding the X-Request-ID of the request
+const RequestIDAttribute = attribute.Key("request.id")
+
+// tracer starts the server spans, it does nothing until tracing.Setup installs a provider
+var tracer = otel.Tracer("feast-friends-api/internal/middleware")
+
+// Tracing starts a server span per request. It must run inside Logs: the span gets the request id
+// and the trace id is written to the access log, so one can be found from the other.
+// The span is named after the route pattern once the mux matched one, e.g "GET /api/v1/posts/{id}".
+func Tracing(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracer.Start(ctx, r.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(r.Method),
+				semconv.URLPath(r.URL.Path),
+				semconv.ClientAddress(clientIP(r)),
+				semconv.UserAgentOriginal(r.UserAgent()),
+				RequestIDAttribute.String(logger.RequestIDFromContext(ctx)),
+			),
+		)
+		defer span.End()
+
+		entry, _ := ctx.Value(accessLogKey).(*accessLog)
+		if entry != nil && span.SpanContext().IsValid() {
+			entry.traceID = span.SpanContext().TraceID().String()
+		}
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+
+		if entry != nil && entry.route != "" {
+			span.SetName(entry.route)
+			if _, path, ok := strings.Cut(entry.route, " "); ok {
+				span.SetAttributes(semconv.HTTPRoute(path))
+			} else {
+				span.SetAttributes(semconv.HTTPRoute(entry.route))
+			}
+		}
+		// Logs wraps the writer, without it the status is unknown
+		if rw, ok := w.(*responseWriter); ok {
+			span.SetAttributes(semconv.HTTPResponseStatusCode(rw.status))
+			if rw.status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(rw.status))
+			}
+		}
+	})
+}
@@ -2,22 +2,40 @@ package models
 import(
 	"feast-friends-api/pkg/helpers"
 	"time"
+
+	"github.com/google/uuid"
 )
 
+// DeletedCommentContent replaces the text of soft deleted comments so replies keep their place in the thread
+const DeletedCommentContent = "[deleted]"
+
 // Comment represents a comment made by a user on a post
 type Comment struct {
-	ID        int    	`json:"id" validate:"required"`      // Unique identifier for the comment
-	UserID    int    	`json:"user_id" validate:"required"` // ID of the user who made the comment
-	PostID    int    	`json:"post_id" validate:"required"` // ID of the post being commented on
+	ID        uuid.UUID 	`json:"id"`                          // Unique identifier for the comment, set by the database
+	UserID    uuid.UUID 	`json:"user_id" validate:"required"` // ID of the user who made the comment
+	PostID    uuid.UUID 	`json:"post_id" validate:"required"` // ID of the post being commented on
+	ParentID  *uuid.UUID	`json:"parent_id,omitempty"`         // ID of the comment this one replies to, nil for top level comments
 	Content   string 	`json:"content" validate:"required,min=1,max=250"` // The comment text, limited to 250 characters
-	CreatedAt time.Time `json:"created_at" validate:"required"`           // Timestamp when the comment was created
+	Deleted   bool   	`json:"deleted"`                     // True once the comment was soft deleted, content is then "[deleted]"
+	CreatedAt time.Time `json:"created_at"`                  // Timestamp when the comment was created
+	UpdatedAt time.Time `json:"updated_at"`                  // Timestamp of the last edit, equal to created_at if never edited
+}
+
+// CommentEdit is an earlier version of a comment, kept when the author edits it
+type CommentEdit struct {
+	ID        uuid.UUID `json:"id"`         // Unique identifier for the edit, set by the database
+	CommentID uuid.UUID `json:"comment_id"` // ID of the edited comment
+	Content   string    `json:"content"`    // The content the comment had before the edit
+	EditedAt  time.Time `json:"edited_at"`  // Timestamp when this content was replaced
 }
 
 // CommentWithUser is a composite structure that embeds a Comment
 // and includes the associated User information
 type CommentWithUser struct {
 	Comment           // Embedded Comment struct
-	User    User `json:"user" validate:"required,dive"` // The user who made the comment
+	User    User `json:"user" validate:"required,dive"` // The user who made the comment, empty for deleted comments
+	ReplyCount int `json:"reply_count"`              // Number of direct replies, including ones cut off by the depth limit
+	Replies []CommentWithUser `json:"replies"`       // Direct replies loaded within the depth limit, oldest first
 }
 
 // Validate checks if the Comment struct fields meet the validation rules
@@ -26,8 +44,13 @@ func (x *Comment) Validate() error {
 	return helpers.ValidateStruct(x)
 }
 
-//timeFormat wil convert the created at timestamp to a human readable formtat 
+//timeFormat wil convert the created at timestamp to a human readable formtat
 //it will return a date in the format "02 Jan 2002 15:04"
 func (x *Comment) TimeFormat() string {
 	return helpers.FormatTime(x.CreatedAt)
 }
+
+// IsEdited reports whether the comment content was changed after it was posted
+func (x *Comment) IsEdited() bool {
+	return !x.Deleted && x.UpdatedAt.After(x.CreatedAt)
+}
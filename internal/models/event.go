@@ -3,20 +3,23 @@ package models
 import (
 	"feast-friends-api/pkg/helpers"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 // Event represents an event created by a user.
 // It contains all the necessary details about the event.
 type Event struct {
-	ID               int       `json:"id" validate:"required"`                        
-	CreatorID        int       `json:"creator_id" validate:"required"`                
-	Title            string    `json:"title" validate:"required,min=3,max=20"`        
-	Description      string    `json:"description" validate:"required,max=500"`       
-	Location         string    `json:"location" validate:"required"`                  
-	EventDate        time.Time `json:"event_date" validate:"required"`         
+	ID               uuid.UUID `json:"id"`                                            // Set by the database
+	CreatorID        uuid.UUID `json:"creator_id" validate:"required"`
+	Title            string    `json:"title" validate:"required,min=3,max=20"`
+	Description      string    `json:"description" validate:"required,max=500"`
+	Location         string    `json:"location" validate:"required"`
+	EventDate        time.Time `json:"event_date" validate:"required"`
 	MaxAttendees     int       `json:"max_attendees" validate:"required,min=1"`       // Maximum number of attendees allowed
 	CurrentAttendees int       `json:"current_attendees"`                             // Current number of attendees
 	ImageURL         string    `json:"image_url"`                                     // Optional image URL for the event
+	CancelledAt      *time.Time `json:"cancelled_at,omitempty"`                       // Set when the creator cancels the event
 	CreatedAt        time.Time `json:"created_at"`                                    // Timestamp when the event was created
 	UpdatedAt        time.Time `json:"updated_at"`                                    // Timestamp when the event was last updated
 }
@@ -27,7 +30,8 @@ type EventRSVP struct {
 	Event
 	User      User      `json:"user" validate:"required,dive"`                        // The user who RSVP'd to the event
 	CreatedAt time.Time `json:"created_at"`                                           // Timestamp when the RSVP was created
-	Statues   string    `json:"statues" validate:"required,oneof=going maybe cancelled"` // Status of the RSVP (going, maybe, cancelled)
+	Statues   RSVPStatus `json:"statues" validate:"required,oneof=going maybe cancelled waitlisted"` // Status of the RSVP (going, maybe, cancelled, waitlisted)
+	WaitlistPosition int `json:"waitlist_position,omitempty"`                          // 1 is next in line, only set while waitlisted
 }
 
 // Validate validates the Event struct fields using the helpers package.
@@ -38,8 +42,18 @@ func (x *Event) Validate() error {
 // TimeFormat returns the formatted creation time of the event.
 func (x *Event) TimeFormat() string {
 	return helpers.FormatTime(x.CreatedAt)
-}	
+}
+
+// IsCancelled reports whether the creator cancelled the event.
+func (x *Event) IsCancelled() bool {
+	return x.CancelledAt != nil
+}
+
+// IsFull reports whether no more attendees can join the event.
+func (x *Event) IsFull() bool {
+	return x.MaxAttendees > 0 && x.CurrentAttendees >= x.MaxAttendees
+}
 
 func (x *EventRSVP) RSVPTimeFormat() string {
 	return helpers.FormatTime(x.CreatedAt)
-}	
\ No newline at end of file
+}
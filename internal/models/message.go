@@ -3,25 +3,36 @@ package models
 import (
 	"feast-friends-api/pkg/helpers"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 // Conversation represents a chat between two users.
+// User1ID is always the smaller id (in postgres uuid order) so each pair of users has a single conversation.
 type Conversation struct {
-	ID            int       `json:"id" validate:"required"`
-	User1ID       int       `json:"user1_id" validate:"required"`
-	LastMessageAt time.Time `json:"last_message_at" validate:"omitempty"`
-	CreatedAt     time.Time `json:"created_at" validate:"required"`
+	ID            uuid.UUID `json:"id"`                               // Set by the database
+	User1ID       uuid.UUID `json:"user1_id" validate:"required"`
+	User2ID       uuid.UUID `json:"user2_id" validate:"required"`
+	LastMessageAt time.Time `json:"last_message_at" validate:"omitempty"` // Equal to created_at until the first message
+	CreatedAt     time.Time `json:"created_at"`
 }
 
+// Message types accepted by Message, image and video messages carry the media url as content
+const (
+	MessageText  = "text"
+	MessageImage = "image"
+	MessageVideo = "video"
+)
+
 // Message represents a single message in a conversation.
 type Message struct {
-	ID             int       `json:"id" validate:"required"`
-	ConversationID int       `json:"conversation_id" validate:"required"` // Use int for foreign key
-	SenderID       int       `json:"sender_id" validate:"required"`       // Fixed typo
-	Content        string    `json:"content" validate:"required,min=1,max=100"` // Fixed JSON tag
-	ReadAt         time.Time `json:"read_at" validate:"required"`
-	MessageType    string    `json:"message_type" validate:"required,oneof=text image video"` // Use string values
-	CreatedAt      time.Time `json:"created_at" validate:"required"`
+	ID             uuid.UUID  `json:"id"`                                   // Set by the database
+	ConversationID uuid.UUID  `json:"conversation_id" validate:"required"`
+	SenderID       uuid.UUID  `json:"sender_id" validate:"required"`       // Fixed typo
+	Content        string     `json:"content" validate:"required,min=1,max=100"` // Fixed JSON tag
+	ReadAt         *time.Time `json:"read_at"`                              // Nil until the other participant reads it
+	MessageType    string     `json:"message_type" validate:"required,oneof=text image video"` // Use string values
+	CreatedAt      time.Time  `json:"created_at"`
 }
 
 // ConversationWithUser combines a conversation with the other user and last message.
@@ -29,6 +40,25 @@ type ConversationWithUser struct {
 	Conversation
 	OtherUser   User     `json:"other_user" validate:"required,dive"`
 	LastMessage *Message `json:"last_message,omitempty"`
+	UnreadCount int      `json:"unread_count"` // Messages from the other user not read yet
+}
+
+// OtherParticipant returns the id of the participant that is not userID.
+func (c *Conversation) OtherParticipant(userID uuid.UUID) uuid.UUID {
+	if c.User1ID == userID {
+		return c.User2ID
+	}
+	return c.User1ID
+}
+
+// HasParticipant reports whether userID is one of the two participants.
+func (c *Conversation) HasParticipant(userID uuid.UUID) bool {
+	return c.User1ID == userID || c.User2ID == userID
+}
+
+// IsRead reports whether the recipient has read the message.
+func (x *Message) IsRead() bool {
+	return x.ReadAt != nil
 }
 
 // Validate validates the Message struct fields.
@@ -3,19 +3,22 @@ package models
 import (
 	"feast-friends-api/pkg/helpers"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 // Post represents a social media post containing recipe information
 type Post struct {
-	ID            int    	`json:"id" validate:"required"`              // Unique identifier for the post
-	UserID        int    	`json:"user_id" validate:"required"`        // ID of the user who created the post
+	ID            uuid.UUID	`json:"id"`                                  // Unique identifier for the post, set by the database
+	UserID        uuid.UUID	`json:"user_id" validate:"required"`        // ID of the user who created the post
 	Title         string 	`json:"title" validate:"omitempty,max=100"` // Optional title of the post, max 100 chars
 	Description   string 	`json:"description" validate:"omitempty,max=400"` // Optional description, max 400 chars
 	ImageURL      string 	`json:"image_url" validate:"omitempty,url"`      // Optional URL to post's image
 	Recipe        Recipe 	`json:"recipe" validate:"required"`          // Recipe details, required
-	LikesCount    int    	`json:"likes_count" validate:"required,min=0"`   // Number of likes, must be non-negative
-	CommentsCount int    	`json:"comments_count" validate:"required,min=0"` // Number of comments, must be non-negative
-	CreatedAt     time.Time `json:"created_at" validate:"required"`          // Timestamp of post creation in RFC3339 format
+	LikesCount    int    	`json:"likes_count" validate:"min=0"`            // Number of likes, must be non-negative
+	CommentsCount int    	`json:"comments_count" validate:"min=0"`          // Number of comments, must be non-negative
+	LikedByMe     *bool  	`json:"liked_by_me,omitempty"`                  // Whether the requesting user likes the post, only set when the request has a token
+	CreatedAt     time.Time `json:"created_at"`                              // Timestamp of post creation in RFC3339 format, set by the database
 }
 
 // Ingredients represents a single ingredient in a recipe
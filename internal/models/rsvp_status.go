@@ -0,0 +1,110 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// RSVPStatus is the status of a users RSVP to an event.
+// The same spelling is used in json and in public.event_rsvps.status.
+type RSVPStatus string
+
+// RSVP statuses accepted by EventRSVP
+const (
+	RSVPGoing     RSVPStatus = "going"
+	RSVPMaybe     RSVPStatus = "maybe"
+	RSVPCancelled RSVPStatus = "cancelled"
+	// RSVPWaitlisted is set by the api when a user asks to go to a full event, it cannot be requested
+	RSVPWaitlisted RSVPStatus = "waitlisted"
+)
+
+// legacyRSVPStatuses maps the spellings used by the first schema to the canonical statuses,
+// they are still accepted on input so older clients and rows keep working
+var legacyRSVPStatuses = map[string]RSVPStatus{
+	"attending":     RSVPGoing,
+	"not_attending": RSVPCancelled,
+}
+
+// ParseRSVPStatus returns the canonical status for s, accepting the legacy spellings.
+// Parsing is case insensitive and ignores surrounding spaces.
+func ParseRSVPStatus(s string) (RSVPStatus, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if status, ok := legacyRSVPStatuses[s]; ok {
+		return status, nil
+	}
+	status := RSVPStatus(s)
+	if !status.IsValid() {
+		return "", fmt.Errorf("invalid rsvp status %q", s)
+	}
+	return status, nil
+}
+
+// IsValid reports whether the status is one of the canonical statuses.
+func (s RSVPStatus) IsValid() bool {
+	switch s {
+	case RSVPGoing, RSVPMaybe, RSVPCancelled, RSVPWaitlisted:
+		return true
+	}
+	return false
+}
+
+// Requestable reports whether users can ask for the status themselves, waitlisted is only set by the api.
+func (s RSVPStatus) Requestable() bool {
+	return s.IsValid() && s != RSVPWaitlisted
+}
+
+// String returns the status as stored and sent to clients.
+func (s RSVPStatus) String() string {
+	return string(s)
+}
+
+// MarshalJSON writes the canonical spelling.
+func (s RSVPStatus) MarshalJSON() ([]byte, error) {
+	return json.Marshal(string(s))
+}
+
+// UnmarshalJSON accepts the canonical and legacy spellings.
+func (s *RSVPStatus) UnmarshalJSON(data []byte) error {
+	var raw string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	status, err := ParseRSVPStatus(raw)
+	if err != nil {
+		return err
+	}
+	*s = status
+	return nil
+}
+
+// Value stores the canonical spelling in the database.
+func (s RSVPStatus) Value() (driver.Value, error) {
+	if !s.IsValid() {
+		return nil, fmt.Errorf("invalid rsvp status %q", string(s))
+	}
+	return string(s), nil
+}
+
+// Scan reads a status from the database, rows written before the migration may still use the legacy spellings.
+func (s *RSVPStatus) Scan(src interface{}) error {
+	var raw string
+	switch v := src.(type) {
+	case nil:
+		*s = ""
+		return nil
+	case string:
+		raw = v
+	case []byte:
+		raw = string(v)
+	default:
+		return fmt.Errorf("cannot scan %T into RSVPStatus", src)
+	}
+	status, err := ParseRSVPStatus(raw)
+	if err != nil {
+		return err
+	}
+	*s = status
+	return nil
+}
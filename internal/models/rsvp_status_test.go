@@ -0,0 +1,124 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestRSVPStatusUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		json    string
+		want    RSVPStatus
+		wantErr bool
+	}{
+		{`"going"`, RSVPGoing, false},
+		{`"maybe"`, RSVPMaybe, false},
+		{`"cancelled"`, RSVPCancelled, false},
+		{`"waitlisted"`, RSVPWaitlisted, false},
+		{`"attending"`, RSVPGoing, false},
+		{`"not_attending"`, RSVPCancelled, false},
+		{`" Attending "`, RSVPGoing, false},
+		{`"GOING"`, RSVPGoing, false},
+		{`"interested"`, "", true},
+		{`""`, "", true},
+		{`1`, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.json, func(t *testing.T) {
+			var got RSVPStatus
+			err := json.Unmarshal([]byte(tt.json), &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Unmarshal(%s) error = %v, wantErr %v", tt.json, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Unmarshal(%s) = %q, want %q", tt.json, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRSVPStatusMarshalJSON(t *testing.T) {
+	var status RSVPStatus
+	if err := json.Unmarshal([]byte(`"not_attending"`), &status); err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(status)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `"cancelled"` {
+		t.Errorf("Marshal = %s, want the canonical spelling", data)
+	}
+}
+
+func TestRSVPStatusScan(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     interface{}
+		want    RSVPStatus
+		wantErr bool
+	}{
+		{"string", "going", RSVPGoing, false},
+		{"bytes", []byte("maybe"), RSVPMaybe, false},
+		{"legacy attending", "attending", RSVPGoing, false},
+		{"legacy not attending", []byte("not_attending"), RSVPCancelled, false},
+		{"null", nil, "", false},
+		{"unknown", "interested", "", true},
+		{"wrong type", 1, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got RSVPStatus
+			err := got.Scan(tt.src)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Scan(%v) error = %v, wantErr %v", tt.src, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Scan(%v) = %q, want %q", tt.src, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRSVPStatusValue(t *testing.T) {
+	tests := []struct {
+		status  RSVPStatus
+		wantErr bool
+	}{
+		{RSVPGoing, false},
+		{RSVPWaitlisted, false},
+		{"attending", true},
+		{"", true},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.status), func(t *testing.T) {
+			got, err := tt.status.Value()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Value() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != string(tt.status) {
+				t.Errorf("Value() = %v, want %q", got, tt.status)
+			}
+		})
+	}
+}
+
+func TestRSVPStatusRequestable(t *testing.T) {
+	tests := []struct {
+		status RSVPStatus
+		want   bool
+	}{
+		{RSVPGoing, true},
+		{RSVPMaybe, true},
+		{RSVPCancelled, true},
+		{RSVPWaitlisted, false},
+		{"attending", false},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.status), func(t *testing.T) {
+			if got := tt.status.Requestable(); got != tt.want {
+				t.Errorf("Requestable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
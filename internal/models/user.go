@@ -12,21 +12,23 @@ import (
 	"strings"
 	"feast-friends-api/pkg/helpers"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 
 
 // the struct that represents a user in the database
 type User struct {
-	ID             int    `json:"id" validate:"required"`
-	Email          string `json:"email" validate:"required,email"`
-	Username       string `json:"username" validate:"required,alphanum,min=3,max=20"`
-	FullName       string `json:"full_name" validate:"required,min=2,max=50"`
-	Bio            string `json:"bio" validate:"omitempty,max=200"`
-	AvatarURL      string `json:"avatar_url" validate:"omitempty,url"`
-	FollowersCount int    `json:"followers_count" validate:"required,min=0"`
-	FollowingCount int    `json:"following_count" validate:"required,min=0"`
-	PostsCount     int    `json:"posts_count" validate:"required,min=0"`
+	ID             uuid.UUID `json:"id" validate:"required"` // Same id as the auth.users row
+	Email          string    `json:"email" validate:"required,email"`
+	Username       string    `json:"username" validate:"required,alphanum,min=3,max=20"`
+	FullName       string    `json:"full_name" validate:"required,min=2,max=50"`
+	Bio            string    `json:"bio" validate:"omitempty,max=200"`
+	AvatarURL      string    `json:"avatar_url" validate:"omitempty,url"`
+	FollowersCount int       `json:"followers_count" validate:"required,min=0"`
+	FollowingCount int       `json:"following_count" validate:"required,min=0"`
+	PostsCount     int       `json:"posts_count" validate:"required,min=0"`
 	CreatedAt      time.Time `json:"created_at" validate:"required"`
 }
 
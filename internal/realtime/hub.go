@@ -0,0 +1,46 @@
+// Package realtime pushes events to connected users (new messages, read receipts, typing indicators, waitlist promotions).
+// handlers publish events to a Hub and the stream endpoint delivers them to the users subscribed on it.
+// LocalHub works inside one process, PostgresHub fans events out through LISTEN/NOTIFY so every
+// replica of the api delivers them to its own connections.
+package realtime
+
+import (
+	"context"
+	"feast-friends-api/internal/models"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Event types sent to clients
+const (
+	// EventMessageCreated carries a new message, it is sent to both participants
+	EventMessageCreated = "message.created"
+	// EventMessagesRead tells the sender that the other participant read the conversation
+	EventMessagesRead = "messages.read"
+	// EventTyping tells the other participant that the user is typing
+	EventTyping = "typing"
+	// EventWaitlistPromoted tells a waitlisted user they got a spot, it carries the event
+	EventWaitlistPromoted = "event.waitlist_promoted"
+)
+
+// Event is the JSON object pushed to clients
+type Event struct {
+	Type           string          `json:"type"`
+	ConversationID uuid.UUID       `json:"conversation_id,omitzero"` // not set for event.waitlist_promoted
+	UserID         uuid.UUID       `json:"user_id"`                  // the user who caused the event
+	Message        *models.Message `json:"message,omitempty"`        // set for message.created
+	ReadAt         *time.Time      `json:"read_at,omitempty"`        // set for messages.read
+	Event          *models.Event   `json:"event,omitempty"`          // set for event.waitlist_promoted
+}
+
+// Hub routes events to the connections of the recipients
+type Hub interface {
+	// Publish delivers the event to every connection of each recipient
+	Publish(ctx context.Context, recipients []uuid.UUID, event Event) error
+	// Subscribe returns a channel receiving the events for userID until unsubscribe is called
+	// the channel is closed on unsubscribe and when the hub is closed
+	Subscribe(userID uuid.UUID) (events <-chan Event, unsubscribe func())
+	// Close ends every subscription, it is called on shutdown so streams do not block it
+	Close()
+}
@@ -0,0 +1,100 @@
+// local.go is the in-process Hub, enough when a single replica of the api is running
+
+package realtime
+
+import (
+	"context"
+	"feast-friends-api/pkg/logger"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// subscriberBuffer is how many events a connection can fall behind before events are dropped
+const subscriberBuffer = 32
+
+// subscriber is one connection of a user
+type subscriber struct {
+	events chan Event
+}
+
+// LocalHub keeps the subscribers of this process in memory
+type LocalHub struct {
+	mu          sync.Mutex
+	subscribers map[uuid.UUID]map[*subscriber]struct{} // user id -> connections
+	closed      bool
+}
+
+// make sure the implementation satisfies the interface at compile time
+var _ Hub = (*LocalHub)(nil)
+
+// NewLocalHub creates a hub without subscribers
+func NewLocalHub() *LocalHub {
+	return &LocalHub{subscribers: make(map[uuid.UUID]map[*subscriber]struct{})}
+}
+
+// Publish hands the event to every connection of the recipients without blocking,
+// a connection that is too slow to keep up misses the event instead of stalling the publisher
+func (h *LocalHub) Publish(ctx context.Context, recipients []uuid.UUID, event Event) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, userID := range recipients {
+		for sub := range h.subscribers[userID] {
+			select {
+			case sub.events <- event:
+			default:
+				logger.Warn("dropped %s event for user %v, subscriber is too slow", event.Type, userID)
+			}
+		}
+	}
+	return nil
+}
+
+// Subscribe registers a new connection for userID
+func (h *LocalHub) Subscribe(userID uuid.UUID) (<-chan Event, func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	sub := &subscriber{events: make(chan Event, subscriberBuffer)}
+	if h.closed {
+		close(sub.events)
+		return sub.events, func() {}
+	}
+
+	if h.subscribers[userID] == nil {
+		h.subscribers[userID] = make(map[*subscriber]struct{})
+	}
+	h.subscribers[userID][sub] = struct{}{}
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			if _, ok := h.subscribers[userID][sub]; !ok {
+				return // already closed by Close
+			}
+			delete(h.subscribers[userID], sub)
+			if len(h.subscribers[userID]) == 0 {
+				delete(h.subscribers, userID)
+			}
+			close(sub.events)
+		})
+	}
+	return sub.events, unsubscribe
+}
+
+// Close closes every subscriber channel and refuses new subscriptions
+func (h *LocalHub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for userID, subs := range h.subscribers {
+		for sub := range subs {
+			close(sub.events)
+		}
+		delete(h.subscribers, userID)
+	}
+}
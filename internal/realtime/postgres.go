@@ -0,0 +1,124 @@
+// postgres.go is the Hub used when several replicas of the api run behind a load balancer
+// events are published with pg_notify and every replica LISTENs on the same channel and
+// delivers them to its own connections through a LocalHub
+
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"feast-friends-api/pkg/logger"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// maxNotifyPayload is the NOTIFY payload limit of postgres (8000 bytes by default)
+const maxNotifyPayload = 8000
+
+// notification is the payload sent through NOTIFY
+type notification struct {
+	Recipients []uuid.UUID `json:"recipients"`
+	Event      Event       `json:"event"`
+}
+
+// PostgresHub publishes through NOTIFY and delivers what it receives on LISTEN locally
+type PostgresHub struct {
+	db      *pgxpool.Pool
+	channel string
+	local   *LocalHub
+}
+
+// make sure the implementation satisfies the interface at compile time
+var _ Hub = (*PostgresHub)(nil)
+
+// NewPostgresHub creates the hub, Run must be started for subscribers to receive anything
+func NewPostgresHub(db *pgxpool.Pool, channel string) *PostgresHub {
+	return &PostgresHub{db: db, channel: channel, local: NewLocalHub()}
+}
+
+// Publish sends the event to every replica, including this one
+func (h *PostgresHub) Publish(ctx context.Context, recipients []uuid.UUID, event Event) error {
+	payload, err := json.Marshal(notification{Recipients: recipients, Event: event})
+	if err != nil {
+		return err
+	}
+	if len(payload) > maxNotifyPayload {
+		logger.Warn("%s event for conversation %v is too big for NOTIFY, delivering locally only", event.Type, event.ConversationID)
+		return h.local.Publish(ctx, recipients, event)
+	}
+
+	_, err = h.db.Exec(ctx, `SELECT pg_notify($1, $2)`, h.channel, string(payload))
+	if err != nil {
+		logger.Error("failed to publish %s event: %v", event.Type, err)
+	}
+	return err
+}
+
+// Subscribe registers a connection on this replica
+func (h *PostgresHub) Subscribe(userID uuid.UUID) (<-chan Event, func()) {
+	return h.local.Subscribe(userID)
+}
+
+// Close ends the subscriptions of this replica
+func (h *PostgresHub) Close() {
+	h.local.Close()
+}
+
+// Run listens for notifications until ctx is cancelled, reconnecting with a growing delay when the connection drops
+func (h *PostgresHub) Run(ctx context.Context) {
+	backoff := time.Second
+	for {
+		started := time.Now()
+		err := h.listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		// a listener that was up for a while starts over with a short delay
+		if time.Since(started) > time.Minute {
+			backoff = time.Second
+		}
+		logger.Error("realtime listener stopped: %v, retrying in %v", err, backoff)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		if backoff < 30*time.Second {
+			backoff *= 2
+		}
+	}
+}
+
+// listen takes a connection out of the pool for LISTEN and forwards notifications to the local hub
+func (h *PostgresHub) listen(ctx context.Context) error {
+	pooled, err := h.db.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	// the connection stays in LISTEN mode so it must not go back to the pool
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{h.channel}.Sanitize()); err != nil {
+		return err
+	}
+	logger.Info("realtime listener subscribed to %s", h.channel)
+
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		var msg notification
+		if err := json.Unmarshal([]byte(n.Payload), &msg); err != nil {
+			logger.Warn("ignoring malformed realtime notification: %v", err)
+			continue
+		}
+		h.local.Publish(ctx, msg.Recipients, msg.Event)
+	}
+}
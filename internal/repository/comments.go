@@ -0,0 +1,40 @@
+// comments.go defines the CommentRepository interface for threaded comments
+// threads are returned flat (oldest first) with ReplyCount filled in, handlers turn them into trees
+
+package repository
+
+import (
+	"context"
+	"errors"
+	"feast-friends-api/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// ErrParentDeleted is returned when replying to a comment that was soft deleted
+var ErrParentDeleted = errors.New("parent comment was deleted")
+
+// CommentRepository describes the operations on public.comments
+type CommentRepository interface {
+	// Create inserts the comment or reply and fills in its id and timestamps
+	// returns ErrNotFound if the post or parent comment does not exist (or the parent is on another post)
+	// and ErrParentDeleted if the parent comment was soft deleted
+	Create(ctx context.Context, comment *models.Comment) error
+	// GetByID returns ErrNotFound if the comment does not exist
+	GetByID(ctx context.Context, id uuid.UUID) (*models.Comment, error)
+	// UpdateContent edits the text of a comment that is not deleted and refreshes UpdatedAt
+	// the previous content is added to the edit history in the same transaction
+	UpdateContent(ctx context.Context, comment *models.Comment) error
+	// SoftDelete marks the comment deleted and replaces its content, replies are kept
+	// the edit history is removed so no earlier version of the text stays readable
+	SoftDelete(ctx context.Context, id uuid.UUID) error
+	// ListThread returns a page of top level comments of the post and their replies up to
+	// maxDepth levels deep, plus the total number of top level comments
+	// returns ErrNotFound if the post does not exist
+	ListThread(ctx context.Context, postID uuid.UUID, limit, offset, maxDepth int) ([]models.CommentWithUser, int, error)
+	// ListReplies returns the replies under the comment up to maxDepth levels deep
+	ListReplies(ctx context.Context, commentID uuid.UUID, maxDepth int) ([]models.CommentWithUser, error)
+	// ListEdits returns the earlier versions of the comment, oldest first
+	// returns ErrNotFound if the comment does not exist
+	ListEdits(ctx context.Context, commentID uuid.UUID) ([]models.CommentEdit, error)
+}
@@ -0,0 +1,228 @@
+// comments_memory.go is an in-memory CommentRepository used by tests and local experiments
+// it keeps comments_count on the in-memory posts the same way the database trigger does
+
+package repository
+
+import (
+	"context"
+	"feast-friends-api/internal/models"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MemoryCommentRepository keeps comments in a map guarded by a mutex
+// authors are looked up in the users registered on the social repository
+type MemoryCommentRepository struct {
+	mu       sync.Mutex
+	posts    *MemoryPostRepository
+	social   *MemorySocialRepository
+	comments map[uuid.UUID]models.Comment
+	edits    map[uuid.UUID][]models.CommentEdit
+}
+
+// make sure the implementation satisfies the interface at compile time
+var _ CommentRepository = (*MemoryCommentRepository)(nil)
+
+// NewMemoryCommentRepository creates an empty repository over the given in-memory posts and users
+func NewMemoryCommentRepository(posts *MemoryPostRepository, social *MemorySocialRepository) *MemoryCommentRepository {
+	return &MemoryCommentRepository{
+		posts:    posts,
+		social:   social,
+		comments: make(map[uuid.UUID]models.Comment),
+		edits:    make(map[uuid.UUID][]models.CommentEdit),
+	}
+}
+
+// Create stores the comment and bumps the post comments_count
+func (r *MemoryCommentRepository) Create(ctx context.Context, comment *models.Comment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if comment.ParentID != nil {
+		parent, ok := r.comments[*comment.ParentID]
+		if !ok || parent.PostID != comment.PostID {
+			return ErrNotFound
+		}
+		if parent.Deleted {
+			return ErrParentDeleted
+		}
+	}
+	if !r.adjustCount(comment.PostID, 1) {
+		return ErrNotFound
+	}
+
+	comment.ID = uuid.New()
+	comment.Deleted = false
+	comment.CreatedAt = time.Now().UTC()
+	comment.UpdatedAt = comment.CreatedAt
+
+	r.comments[comment.ID] = *comment
+	return nil
+}
+
+// GetByID returns a copy of the comment or ErrNotFound
+func (r *MemoryCommentRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Comment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	comment, ok := r.comments[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &comment, nil
+}
+
+// UpdateContent changes the content of a comment that is not deleted and keeps the old content as an edit
+func (r *MemoryCommentRepository) UpdateContent(ctx context.Context, comment *models.Comment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.comments[comment.ID]
+	if !ok || stored.Deleted {
+		return ErrNotFound
+	}
+	now := time.Now().UTC()
+	r.edits[comment.ID] = append(r.edits[comment.ID], models.CommentEdit{
+		ID:        uuid.New(),
+		CommentID: comment.ID,
+		Content:   stored.Content,
+		EditedAt:  now,
+	})
+	stored.Content = comment.Content
+	stored.UpdatedAt = now
+	r.comments[comment.ID] = stored
+
+	comment.UpdatedAt = stored.UpdatedAt
+	return nil
+}
+
+// SoftDelete marks the comment deleted and decrements the post comments_count once
+func (r *MemoryCommentRepository) SoftDelete(ctx context.Context, id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.comments[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if stored.Deleted {
+		return nil
+	}
+	stored.Deleted = true
+	stored.Content = models.DeletedCommentContent
+	stored.UpdatedAt = time.Now().UTC()
+	r.comments[id] = stored
+	delete(r.edits, id)
+	r.adjustCount(stored.PostID, -1)
+	return nil
+}
+
+// ListThread returns a page of top level comments of the post and their replies
+func (r *MemoryCommentRepository) ListThread(ctx context.Context, postID uuid.UUID, limit, offset, maxDepth int) ([]models.CommentWithUser, int, error) {
+	limit, offset = clampPage(limit, offset)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.adjustCount(postID, 0) {
+		return nil, 0, ErrNotFound
+	}
+
+	roots := r.sorted(func(c models.Comment) bool { return c.PostID == postID && c.ParentID == nil })
+	total := len(roots)
+	if offset >= total {
+		return []models.CommentWithUser{}, total, nil
+	}
+	if offset+limit < total {
+		roots = roots[offset : offset+limit]
+	} else {
+		roots = roots[offset:]
+	}
+	return r.walk(roots, maxDepth), total, nil
+}
+
+// ListReplies returns the replies under the comment
+func (r *MemoryCommentRepository) ListReplies(ctx context.Context, commentID uuid.UUID, maxDepth int) ([]models.CommentWithUser, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	replies := r.sorted(func(c models.Comment) bool { return c.ParentID != nil && *c.ParentID == commentID })
+	return r.walk(replies, maxDepth-1), nil
+}
+
+// ListEdits returns a copy of the edit history of the comment, oldest first
+func (r *MemoryCommentRepository) ListEdits(ctx context.Context, commentID uuid.UUID) ([]models.CommentEdit, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.comments[commentID]; !ok {
+		return nil, ErrNotFound
+	}
+	return append([]models.CommentEdit{}, r.edits[commentID]...), nil
+}
+
+// walk returns the roots and their descendants up to maxDepth levels, oldest first
+// callers must hold the lock
+func (r *MemoryCommentRepository) walk(roots []models.Comment, maxDepth int) []models.CommentWithUser {
+	flat := []models.CommentWithUser{}
+	level := roots
+	for depth := 0; len(level) > 0 && depth <= maxDepth; depth++ {
+		next := []models.Comment{}
+		for _, c := range level {
+			children := r.sorted(func(child models.Comment) bool { return child.ParentID != nil && *child.ParentID == c.ID })
+			item := models.CommentWithUser{Comment: c, ReplyCount: len(children), Replies: []models.CommentWithUser{}}
+			// deleted comments keep their place in the thread but not their author
+			if c.Deleted {
+				item.UserID = uuid.Nil
+			} else {
+				item.User = r.social.user(c.UserID)
+			}
+			flat = append(flat, item)
+			next = append(next, children...)
+		}
+		level = next
+	}
+
+	sort.Slice(flat, func(i, j int) bool {
+		if !flat[i].CreatedAt.Equal(flat[j].CreatedAt) {
+			return flat[i].CreatedAt.Before(flat[j].CreatedAt)
+		}
+		return idLess(flat[i].ID, flat[j].ID)
+	})
+	return flat
+}
+
+// sorted returns the comments keep accepts, oldest first
+// callers must hold the lock
+func (r *MemoryCommentRepository) sorted(keep func(models.Comment) bool) []models.Comment {
+	matched := []models.Comment{}
+	for _, c := range r.comments {
+		if keep(c) {
+			matched = append(matched, c)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		if !matched[i].CreatedAt.Equal(matched[j].CreatedAt) {
+			return matched[i].CreatedAt.Before(matched[j].CreatedAt)
+		}
+		return idLess(matched[i].ID, matched[j].ID)
+	})
+	return matched
+}
+
+// adjustCount changes the comments_count of the post, returns false if the post does not exist
+// a delta of 0 only checks that the post exists
+func (r *MemoryCommentRepository) adjustCount(postID uuid.UUID, delta int) bool {
+	r.posts.mu.Lock()
+	defer r.posts.mu.Unlock()
+
+	post, ok := r.posts.posts[postID]
+	if !ok {
+		return false
+	}
+	post.CommentsCount += delta
+	r.posts.posts[postID] = post
+	return true
+}
@@ -0,0 +1,266 @@
+// comments_postgres.go is the CommentRepository implementation backed by the pgx connection pool
+// comments_count on posts and the same-post parent check are handled by the triggers in 003_comment_threads.sql
+// edits keep the replaced content in public.comment_edits (010_comment_edits.sql)
+
+package repository
+
+import (
+	"context"
+	"errors"
+	"feast-friends-api/internal/models"
+	"feast-friends-api/internal/utils"
+	"feast-friends-api/pkg/logger"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// columns selected for every comment query, the comments table is always aliased as c
+const commentColumns = `c.id, c.user_id, c.post_id, c.parent_id, c.content, c.deleted_at, c.created_at, COALESCE(c.updated_at, c.created_at)`
+
+// PostgresCommentRepository reads and writes comments in public.comments
+type PostgresCommentRepository struct {
+	db *pgxpool.Pool
+}
+
+// make sure the implementation satisfies the interface at compile time
+var _ CommentRepository = (*PostgresCommentRepository)(nil)
+
+// NewPostgresCommentRepository creates the repository using the given pool (usually utils.DB)
+func NewPostgresCommentRepository(db *pgxpool.Pool) *PostgresCommentRepository {
+	return &PostgresCommentRepository{db: db}
+}
+
+// Create inserts the comment, the parent check trigger rejects parents from other posts
+// the parent row is locked so it cannot be soft deleted between the check and the insert
+func (r *PostgresCommentRepository) Create(ctx context.Context, comment *models.Comment) error {
+	err := utils.WithTx(ctx, r.db, func(tx pgx.Tx) error {
+		if comment.ParentID != nil {
+			var parentDeleted bool
+			err := utils.ExecuteQueryRowContext(ctx, tx,
+				`SELECT deleted_at IS NOT NULL FROM public.comments WHERE id = $1 FOR SHARE`, *comment.ParentID,
+			).Scan(&parentDeleted)
+			if err != nil {
+				return err
+			}
+			if parentDeleted {
+				return ErrParentDeleted
+			}
+		}
+
+		return utils.ExecuteQueryRowContext(ctx, tx,
+			`INSERT INTO public.comments (user_id, post_id, parent_id, content)
+			 VALUES ($1, $2, $3, $4)
+			 RETURNING id, created_at, COALESCE(updated_at, created_at)`,
+			comment.UserID, comment.PostID, comment.ParentID, comment.Content,
+		).Scan(&comment.ID, &comment.CreatedAt, &comment.UpdatedAt)
+	})
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, ErrParentDeleted):
+		return err
+	case errors.Is(err, pgx.ErrNoRows), pgErrorCode(err) == pgForeignKeyViolation:
+		return ErrNotFound
+	}
+	logger.ErrorContext(ctx, "failed to create comment: %v", err)
+	return err
+}
+
+// GetByID returns the comment with the given id or ErrNotFound
+func (r *PostgresCommentRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Comment, error) {
+	var comment models.Comment
+	var deletedAt *time.Time
+
+	err := utils.ExecuteQueryRowContext(ctx, r.db, `SELECT `+commentColumns+` FROM public.comments c WHERE c.id = $1`, id).
+		Scan(commentDest(&comment, &deletedAt)...)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to get comment %v: %v", id, err)
+		return nil, err
+	}
+	comment.Deleted = deletedAt != nil
+	return &comment, nil
+}
+
+// UpdateContent saves the new content, the updated_at trigger records when the edit happened
+// the row is locked while the replaced content is copied to comment_edits so concurrent edits keep every version
+func (r *PostgresCommentRepository) UpdateContent(ctx context.Context, comment *models.Comment) error {
+	err := utils.WithTx(ctx, r.db, func(tx pgx.Tx) error {
+		var previous string
+		err := utils.ExecuteQueryRowContext(ctx, tx,
+			`SELECT content FROM public.comments WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, comment.ID,
+		).Scan(&previous)
+		if err != nil {
+			return err
+		}
+
+		if _, err := utils.ExecuteNonQueryContext(ctx, tx,
+			`INSERT INTO public.comment_edits (comment_id, content) VALUES ($1, $2)`, comment.ID, previous,
+		); err != nil {
+			return err
+		}
+
+		return utils.ExecuteQueryRowContext(ctx, tx,
+			`UPDATE public.comments SET content = $2 WHERE id = $1 RETURNING updated_at`,
+			comment.ID, comment.Content,
+		).Scan(&comment.UpdatedAt)
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to update comment %v: %v", comment.ID, err)
+		return err
+	}
+	return nil
+}
+
+// SoftDelete blanks the content, drops the edit history and sets deleted_at, deleting twice is a no-op
+func (r *PostgresCommentRepository) SoftDelete(ctx context.Context, id uuid.UUID) error {
+	tag, err := utils.ExecuteNonQueryContext(ctx, r.db,
+		`WITH edits AS (DELETE FROM public.comment_edits WHERE comment_id = $1)
+		 UPDATE public.comments SET content = $2, deleted_at = COALESCE(deleted_at, now())
+		 WHERE id = $1`,
+		id, models.DeletedCommentContent,
+	)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to delete comment %v: %v", id, err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ListThread loads a page of top level comments of the post and their replies
+func (r *PostgresCommentRepository) ListThread(ctx context.Context, postID uuid.UUID, limit, offset, maxDepth int) ([]models.CommentWithUser, int, error) {
+	limit, offset = clampPage(limit, offset)
+	var exists bool
+	var total int
+	err := utils.ExecuteQueryRowContext(ctx, r.db,
+		`SELECT EXISTS (SELECT 1 FROM public.posts WHERE id = $1),
+			(SELECT count(*) FROM public.comments WHERE post_id = $1 AND parent_id IS NULL)`, postID,
+	).Scan(&exists, &total)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to count comments of post %v: %v", postID, err)
+		return nil, 0, err
+	}
+	if !exists {
+		return nil, 0, ErrNotFound
+	}
+
+	comments, err := r.listTree(ctx,
+		`SELECT id FROM public.comments
+		 WHERE post_id = $1 AND parent_id IS NULL
+		 ORDER BY created_at, id
+		 LIMIT $3 OFFSET $4`,
+		postID, maxDepth, limit, offset,
+	)
+	return comments, total, err
+}
+
+// ListReplies loads the replies under the comment
+// the direct replies already are the first level so the recursion goes one level less
+func (r *PostgresCommentRepository) ListReplies(ctx context.Context, commentID uuid.UUID, maxDepth int) ([]models.CommentWithUser, error) {
+	return r.listTree(ctx, `SELECT id FROM public.comments WHERE parent_id = $1`, commentID, maxDepth-1)
+}
+
+// ListEdits returns the edit history of the comment, oldest first
+func (r *PostgresCommentRepository) ListEdits(ctx context.Context, commentID uuid.UUID) ([]models.CommentEdit, error) {
+	var exists bool
+	err := utils.ExecuteQueryRowContext(ctx, r.db, `SELECT EXISTS (SELECT 1 FROM public.comments WHERE id = $1)`, commentID).Scan(&exists)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to check comment %v: %v", commentID, err)
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrNotFound
+	}
+
+	rows, err := utils.ExecuteQueryContext(ctx, r.db,
+		`SELECT id, comment_id, content, edited_at FROM public.comment_edits
+		 WHERE comment_id = $1
+		 ORDER BY edited_at, id`, commentID,
+	)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to load edits of comment %v: %v", commentID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	edits := []models.CommentEdit{}
+	for rows.Next() {
+		var edit models.CommentEdit
+		if err := rows.Scan(&edit.ID, &edit.CommentID, &edit.Content, &edit.EditedAt); err != nil {
+			logger.ErrorContext(ctx, "failed to scan comment edit: %v", err)
+			return nil, err
+		}
+		edits = append(edits, edit)
+	}
+	return edits, rows.Err()
+}
+
+// listTree walks the thread down from the comments selected by rootsQuery with a recursive CTE
+// rootsQuery gets $1 as the id to filter on, $2 is always the depth limit and any extra args follow
+func (r *PostgresCommentRepository) listTree(ctx context.Context, rootsQuery string, id uuid.UUID, maxDepth int, extra ...interface{}) ([]models.CommentWithUser, error) {
+	args := append([]interface{}{id, maxDepth}, extra...)
+
+	rows, err := utils.ExecuteQueryContext(ctx, r.db,
+		`WITH RECURSIVE thread AS (
+			SELECT c.*, 0 AS depth FROM public.comments c WHERE c.id IN (`+rootsQuery+`)
+			UNION ALL
+			SELECT c.*, t.depth + 1 FROM public.comments c
+			JOIN thread t ON c.parent_id = t.id
+			WHERE t.depth < $2
+		)
+		SELECT `+commentColumns+`,
+			(SELECT count(*) FROM public.comments rc WHERE rc.parent_id = c.id),
+			`+userColumns+`
+		FROM thread c
+		JOIN `+userFrom+` ON p.id = c.user_id
+		ORDER BY c.created_at, c.id`,
+		args...,
+	)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to load comment thread for %v: %v", id, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	comments := []models.CommentWithUser{}
+	for rows.Next() {
+		var item models.CommentWithUser
+		var deletedAt *time.Time
+
+		dest := append(commentDest(&item.Comment, &deletedAt), &item.ReplyCount)
+		dest = append(dest, userDest(&item.User)...)
+		if err := rows.Scan(dest...); err != nil {
+			logger.ErrorContext(ctx, "failed to scan comment: %v", err)
+			return nil, err
+		}
+
+		// deleted comments keep their place in the thread but not their author
+		if deletedAt != nil {
+			item.Deleted = true
+			item.UserID = uuid.Nil
+			item.User = models.User{}
+		}
+		item.Replies = []models.CommentWithUser{}
+		comments = append(comments, item)
+	}
+	return comments, rows.Err()
+}
+
+// commentDest returns the scan destinations matching commentColumns
+func commentDest(comment *models.Comment, deletedAt **time.Time) []interface{} {
+	return []interface{}{
+		&comment.ID, &comment.UserID, &comment.PostID, &comment.ParentID, &comment.Content,
+		deletedAt, &comment.CreatedAt, &comment.UpdatedAt,
+	}
+}
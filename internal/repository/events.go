@@ -0,0 +1,59 @@
+// events.go defines the EventRepository interface for events and their RSVPs
+// RSVPs are capacity checked atomically: two users racing for the last spot cannot both get it
+// users asking to go to a full event are waitlisted and promoted in order when a spot frees up
+
+package repository
+
+import (
+	"context"
+	"errors"
+	"feast-friends-api/internal/models"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrEventFull is returned when the capacity constraint is hit outside the waitlist flow
+	ErrEventFull = errors.New("event is full")
+	// ErrWaitlistMismatch is returned when a reorder does not list exactly the waitlisted users
+	ErrWaitlistMismatch = errors.New("user ids do not match the waitlist")
+	// ErrEventCancelled is returned when changing or RSVPing to a cancelled event
+	ErrEventCancelled = errors.New("event is cancelled")
+	// ErrCapacityTooLow is returned when max_attendees is set below the current attendees
+	ErrCapacityTooLow = errors.New("max attendees is lower than current attendees")
+)
+
+// RSVPResult is what an RSVP ended up as
+type RSVPResult struct {
+	Event *models.Event `json:"event"`
+	// Status is the stored status, waitlisted when going was asked for a full event
+	Status models.RSVPStatus `json:"status"`
+	// WaitlistPosition is 1 for the next user in line, 0 when not waitlisted
+	WaitlistPosition int `json:"waitlist_position,omitempty"`
+	// Promoted holds the users moved from the waitlist to going because of this change
+	Promoted []uuid.UUID `json:"-"`
+}
+
+// EventRepository describes the operations on public.events and public.event_rsvps
+type EventRepository interface {
+	// Create inserts the event and fills in its id and timestamps
+	Create(ctx context.Context, event *models.Event) error
+	// GetByID returns ErrNotFound if the event does not exist
+	GetByID(ctx context.Context, id uuid.UUID) (*models.Event, error)
+	// ListUpcoming returns a page of events that are not cancelled and have not happened yet, soonest first
+	ListUpcoming(ctx context.Context, limit, offset int) ([]models.Event, int, error)
+	// Update saves the editable fields, returns ErrEventCancelled or ErrCapacityTooLow when not allowed
+	// raising max_attendees promotes waitlisted users into the new spots, their ids are returned
+	Update(ctx context.Context, event *models.Event) ([]uuid.UUID, error)
+	// Cancel marks the event cancelled, cancelling twice is a no-op
+	Cancel(ctx context.Context, id uuid.UUID) error
+	// RSVP sets the users status for the event, going on a full event puts the user on the waitlist
+	// and a going user dropping out promotes the first waitlisted user
+	RSVP(ctx context.Context, eventID, userID uuid.UUID, status models.RSVPStatus) (*RSVPResult, error)
+	// ListAttendees returns a page of the going and maybe RSVPs, oldest first, plus the total count
+	ListAttendees(ctx context.Context, eventID uuid.UUID, limit, offset int) ([]models.EventRSVP, int, error)
+	// ListWaitlist returns the waitlisted RSVPs in the order they will be promoted
+	ListWaitlist(ctx context.Context, eventID uuid.UUID) ([]models.EventRSVP, error)
+	// ReorderWaitlist sets the promotion order, userIDs must be exactly the waitlisted users
+	ReorderWaitlist(ctx context.Context, eventID uuid.UUID, userIDs []uuid.UUID) error
+}
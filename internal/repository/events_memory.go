@@ -0,0 +1,334 @@
+// events_memory.go is an in-memory EventRepository used by tests and local experiments
+// a single mutex makes every RSVP atomic, the same guarantee the row lock gives in postgres
+
+package repository
+
+import (
+	"context"
+	"feast-friends-api/internal/models"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// memoryRSVP is a stored RSVP, position is only set while waitlisted
+type memoryRSVP struct {
+	status    models.RSVPStatus
+	position  int
+	createdAt time.Time
+}
+
+// MemoryEventRepository keeps events and RSVPs in maps guarded by a mutex
+// attendees are looked up in the users registered on the social repository
+type MemoryEventRepository struct {
+	mu           sync.Mutex
+	social       *MemorySocialRepository
+	events       map[uuid.UUID]models.Event
+	rsvps        map[uuid.UUID]map[uuid.UUID]memoryRSVP // event id -> user id -> rsvp
+	nextPosition int
+}
+
+// make sure the implementation satisfies the interface at compile time
+var _ EventRepository = (*MemoryEventRepository)(nil)
+
+// NewMemoryEventRepository creates an empty repository using the users registered on social
+func NewMemoryEventRepository(social *MemorySocialRepository) *MemoryEventRepository {
+	return &MemoryEventRepository{
+		social: social,
+		events: make(map[uuid.UUID]models.Event),
+		rsvps:  make(map[uuid.UUID]map[uuid.UUID]memoryRSVP),
+	}
+}
+
+// Create stores the event and fills in its id and timestamps
+func (r *MemoryEventRepository) Create(ctx context.Context, event *models.Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	event.ID = uuid.New()
+	event.CurrentAttendees = 0
+	event.CancelledAt = nil
+	event.CreatedAt = time.Now().UTC()
+	event.UpdatedAt = event.CreatedAt
+
+	r.events[event.ID] = *event
+	r.rsvps[event.ID] = make(map[uuid.UUID]memoryRSVP)
+	return nil
+}
+
+// GetByID returns a copy of the event or ErrNotFound
+func (r *MemoryEventRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Event, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	event, ok := r.events[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &event, nil
+}
+
+// ListUpcoming returns the events that are still going to happen, soonest first
+func (r *MemoryEventRepository) ListUpcoming(ctx context.Context, limit, offset int) ([]models.Event, int, error) {
+	limit, offset = clampPage(limit, offset)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	upcoming := []models.Event{}
+	for _, e := range r.events {
+		if !e.IsCancelled() && !e.EventDate.Before(now) {
+			upcoming = append(upcoming, e)
+		}
+	}
+	sort.Slice(upcoming, func(i, j int) bool {
+		if !upcoming[i].EventDate.Equal(upcoming[j].EventDate) {
+			return upcoming[i].EventDate.Before(upcoming[j].EventDate)
+		}
+		return idLess(upcoming[i].ID, upcoming[j].ID)
+	})
+
+	total := len(upcoming)
+	if offset >= total {
+		return []models.Event{}, total, nil
+	}
+	if offset+limit < total {
+		return upcoming[offset : offset+limit], total, nil
+	}
+	return upcoming[offset:], total, nil
+}
+
+// Update saves the editable fields of the stored event and fills new spots from the waitlist
+func (r *MemoryEventRepository) Update(ctx context.Context, event *models.Event) ([]uuid.UUID, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.events[event.ID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if stored.IsCancelled() {
+		return nil, ErrEventCancelled
+	}
+	if event.MaxAttendees < stored.CurrentAttendees {
+		return nil, ErrCapacityTooLow
+	}
+
+	stored.Title = event.Title
+	stored.Description = event.Description
+	stored.Location = event.Location
+	stored.EventDate = event.EventDate
+	stored.MaxAttendees = event.MaxAttendees
+	stored.ImageURL = event.ImageURL
+	stored.UpdatedAt = time.Now().UTC()
+
+	promoted := r.promote(&stored, stored.MaxAttendees-stored.CurrentAttendees)
+	r.events[event.ID] = stored
+
+	event.CurrentAttendees = stored.CurrentAttendees
+	event.UpdatedAt = stored.UpdatedAt
+	return promoted, nil
+}
+
+// Cancel sets CancelledAt once
+func (r *MemoryEventRepository) Cancel(ctx context.Context, id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.events[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if !stored.IsCancelled() {
+		now := time.Now().UTC()
+		stored.CancelledAt = &now
+		r.events[id] = stored
+	}
+	return nil
+}
+
+// RSVP sets the users status and keeps CurrentAttendees and the waitlist in sync
+func (r *MemoryEventRepository) RSVP(ctx context.Context, eventID, userID uuid.UUID, status models.RSVPStatus) (*RSVPResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	event, ok := r.events[eventID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if event.IsCancelled() {
+		return nil, ErrEventCancelled
+	}
+
+	result := &RSVPResult{Status: status}
+	previous, existed := r.rsvps[eventID][userID]
+	wasGoing := existed && previous.status == models.RSVPGoing
+
+	if status == models.RSVPGoing && !wasGoing && event.IsFull() {
+		result.Status = models.RSVPWaitlisted
+	}
+
+	rsvp := memoryRSVP{status: result.Status, createdAt: time.Now().UTC()}
+	if existed {
+		rsvp.createdAt = previous.createdAt
+	}
+	if result.Status == models.RSVPWaitlisted {
+		if existed && previous.status == models.RSVPWaitlisted {
+			rsvp.position = previous.position
+		} else {
+			r.nextPosition++
+			rsvp.position = r.nextPosition
+		}
+	}
+	r.rsvps[eventID][userID] = rsvp
+
+	willGo := result.Status == models.RSVPGoing
+	if willGo && !wasGoing {
+		event.CurrentAttendees++
+	} else if wasGoing && !willGo {
+		event.CurrentAttendees--
+		result.Promoted = r.promote(&event, 1)
+	}
+	r.events[eventID] = event
+
+	if result.Status == models.RSVPWaitlisted {
+		for i, rs := range r.waitlist(eventID) {
+			if rs.userID == userID {
+				result.WaitlistPosition = i + 1
+			}
+		}
+	}
+	result.Event = &event
+	return result, nil
+}
+
+// ListAttendees returns the going and maybe RSVPs with their users, oldest first
+func (r *MemoryEventRepository) ListAttendees(ctx context.Context, eventID uuid.UUID, limit, offset int) ([]models.EventRSVP, int, error) {
+	limit, offset = clampPage(limit, offset)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	event, ok := r.events[eventID]
+	if !ok {
+		return nil, 0, ErrNotFound
+	}
+
+	attendees := []models.EventRSVP{}
+	for userID, rsvp := range r.rsvps[eventID] {
+		if rsvp.status != models.RSVPGoing && rsvp.status != models.RSVPMaybe {
+			continue
+		}
+		attendees = append(attendees, models.EventRSVP{
+			Event:     event,
+			User:      r.social.user(userID),
+			CreatedAt: rsvp.createdAt,
+			Statues:   rsvp.status,
+		})
+	}
+	sort.Slice(attendees, func(i, j int) bool {
+		if !attendees[i].CreatedAt.Equal(attendees[j].CreatedAt) {
+			return attendees[i].CreatedAt.Before(attendees[j].CreatedAt)
+		}
+		return idLess(attendees[i].User.ID, attendees[j].User.ID)
+	})
+
+	total := len(attendees)
+	if offset >= total {
+		return []models.EventRSVP{}, total, nil
+	}
+	if offset+limit < total {
+		return attendees[offset : offset+limit], total, nil
+	}
+	return attendees[offset:], total, nil
+}
+
+// ListWaitlist returns the waitlisted RSVPs with their users in promotion order
+func (r *MemoryEventRepository) ListWaitlist(ctx context.Context, eventID uuid.UUID) ([]models.EventRSVP, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	event, ok := r.events[eventID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	waitlist := []models.EventRSVP{}
+	for i, w := range r.waitlist(eventID) {
+		waitlist = append(waitlist, models.EventRSVP{
+			Event:            event,
+			User:             r.social.user(w.userID),
+			CreatedAt:        w.createdAt,
+			Statues:          models.RSVPWaitlisted,
+			WaitlistPosition: i + 1,
+		})
+	}
+	return waitlist, nil
+}
+
+// ReorderWaitlist sets the promotion order to the order of userIDs
+func (r *MemoryEventRepository) ReorderWaitlist(ctx context.Context, eventID uuid.UUID, userIDs []uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	event, ok := r.events[eventID]
+	if !ok {
+		return ErrNotFound
+	}
+	if event.IsCancelled() {
+		return ErrEventCancelled
+	}
+
+	current := []uuid.UUID{}
+	for _, w := range r.waitlist(eventID) {
+		current = append(current, w.userID)
+	}
+	if !sameIDs(current, userIDs) {
+		return ErrWaitlistMismatch
+	}
+
+	for i, id := range userIDs {
+		rsvp := r.rsvps[eventID][id]
+		rsvp.position = i + 1
+		r.rsvps[eventID][id] = rsvp
+	}
+	return nil
+}
+
+// waitlisted is a waitlisted RSVP with its user id
+type waitlisted struct {
+	memoryRSVP
+	userID uuid.UUID
+}
+
+// waitlist returns the waitlisted RSVPs of the event in promotion order
+// callers must hold the lock
+func (r *MemoryEventRepository) waitlist(eventID uuid.UUID) []waitlisted {
+	list := []waitlisted{}
+	for userID, rsvp := range r.rsvps[eventID] {
+		if rsvp.status == models.RSVPWaitlisted {
+			list = append(list, waitlisted{memoryRSVP: rsvp, userID: userID})
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].position < list[j].position })
+	return list
+}
+
+// promote moves up to n waitlisted users of the event to going and updates its attendee count
+// callers must hold the lock and store the event afterwards
+func (r *MemoryEventRepository) promote(event *models.Event, n int) []uuid.UUID {
+	promoted := []uuid.UUID{}
+	for _, w := range r.waitlist(event.ID) {
+		if len(promoted) >= n {
+			break
+		}
+		rsvp := w.memoryRSVP
+		rsvp.status = models.RSVPGoing
+		rsvp.position = 0
+		r.rsvps[event.ID][w.userID] = rsvp
+		event.CurrentAttendees++
+		promoted = append(promoted, w.userID)
+	}
+	return promoted
+}
@@ -0,0 +1,446 @@
+// events_postgres.go is the EventRepository implementation backed by the pgx connection pool
+// RSVPs run in a transaction that locks the event row so capacity checks and waitlist promotions cannot race
+
+package repository
+
+import (
+	"context"
+	"errors"
+	"feast-friends-api/internal/models"
+	"feast-friends-api/internal/utils"
+	"feast-friends-api/pkg/logger"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// columns selected for every event query, the events table is always aliased as e
+const eventColumns = `e.id, e.creator_id, e.title, COALESCE(e.description, ''), COALESCE(e.location, ''), e.event_date,
+	COALESCE(e.max_attendees, 0), e.current_attendees, COALESCE(e.image_url, ''), e.cancelled_at,
+	e.created_at, COALESCE(e.updated_at, e.created_at)`
+
+// PostgresEventRepository reads and writes events and RSVPs
+type PostgresEventRepository struct {
+	db *pgxpool.Pool
+}
+
+// make sure the implementation satisfies the interface at compile time
+var _ EventRepository = (*PostgresEventRepository)(nil)
+
+// NewPostgresEventRepository creates the repository using the given pool (usually utils.DB)
+func NewPostgresEventRepository(db *pgxpool.Pool) *PostgresEventRepository {
+	return &PostgresEventRepository{db: db}
+}
+
+// Create inserts the event
+func (r *PostgresEventRepository) Create(ctx context.Context, event *models.Event) error {
+	err := utils.ExecuteQueryRowContext(ctx, r.db,
+		`INSERT INTO public.events (creator_id, title, description, location, event_date, max_attendees, image_url)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)
+		 RETURNING id, current_attendees, created_at, COALESCE(updated_at, created_at)`,
+		event.CreatorID, event.Title, event.Description, event.Location, event.EventDate, event.MaxAttendees, event.ImageURL,
+	).Scan(&event.ID, &event.CurrentAttendees, &event.CreatedAt, &event.UpdatedAt)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to create event: %v", err)
+		return err
+	}
+	return nil
+}
+
+// GetByID returns the event with the given id or ErrNotFound
+func (r *PostgresEventRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Event, error) {
+	event, err := scanEvent(utils.ExecuteQueryRowContext(ctx, r.db, `SELECT `+eventColumns+` FROM public.events e WHERE e.id = $1`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to get event %v: %v", id, err)
+		return nil, err
+	}
+	return event, nil
+}
+
+// ListUpcoming returns the events that are still going to happen, soonest first
+func (r *PostgresEventRepository) ListUpcoming(ctx context.Context, limit, offset int) ([]models.Event, int, error) {
+	limit, offset = clampPage(limit, offset)
+	const upcoming = `e.cancelled_at IS NULL AND e.event_date >= now()`
+
+	var total int
+	if err := utils.ExecuteQueryRowContext(ctx, r.db, `SELECT count(*) FROM public.events e WHERE `+upcoming).Scan(&total); err != nil {
+		logger.ErrorContext(ctx, "failed to count events: %v", err)
+		return nil, 0, err
+	}
+
+	rows, err := utils.ExecuteQueryContext(ctx, r.db,
+		`SELECT `+eventColumns+` FROM public.events e
+		 WHERE `+upcoming+`
+		 ORDER BY e.event_date, e.id
+		 LIMIT $1 OFFSET $2`,
+		limit, offset,
+	)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to list events: %v", err)
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	events := []models.Event{}
+	for rows.Next() {
+		event, err := scanEvent(rows)
+		if err != nil {
+			logger.ErrorContext(ctx, "failed to scan event: %v", err)
+			return nil, 0, err
+		}
+		events = append(events, *event)
+	}
+	return events, total, rows.Err()
+}
+
+// Update saves the editable fields, the capacity CHECK rejects a max below the current attendees
+// if the new max leaves free spots the first waitlisted users are promoted in the same transaction
+func (r *PostgresEventRepository) Update(ctx context.Context, event *models.Event) ([]uuid.UUID, error) {
+	var promoted []uuid.UUID
+
+	err := utils.WithTx(ctx, r.db, func(tx pgx.Tx) error {
+		promoted = nil // WithTx may run this again after a deadlock
+		err := utils.ExecuteQueryRowContext(ctx, tx,
+			`UPDATE public.events
+			 SET title = $2, description = $3, location = $4, event_date = $5, max_attendees = $6, image_url = $7
+			 WHERE id = $1 AND cancelled_at IS NULL
+			 RETURNING current_attendees, updated_at`,
+			event.ID, event.Title, event.Description, event.Location, event.EventDate, event.MaxAttendees, event.ImageURL,
+		).Scan(&event.CurrentAttendees, &event.UpdatedAt)
+		if err != nil {
+			return err
+		}
+
+		if free := event.MaxAttendees - event.CurrentAttendees; free > 0 {
+			promoted, err = promoteWaitlisted(ctx, tx, event, free)
+		}
+		return err
+	})
+
+	switch {
+	case err == nil:
+		return promoted, nil
+	case pgErrorCode(err) == pgCheckViolation:
+		return nil, ErrCapacityTooLow
+	case errors.Is(err, pgx.ErrNoRows):
+		if _, getErr := r.GetByID(ctx, event.ID); getErr != nil {
+			return nil, getErr
+		}
+		return nil, ErrEventCancelled
+	}
+	logger.ErrorContext(ctx, "failed to update event %v: %v", event.ID, err)
+	return nil, err
+}
+
+// Cancel sets cancelled_at, RSVPs are kept so attendees can still see the event they signed up for
+func (r *PostgresEventRepository) Cancel(ctx context.Context, id uuid.UUID) error {
+	tag, err := utils.ExecuteNonQueryContext(ctx, r.db, `UPDATE public.events SET cancelled_at = COALESCE(cancelled_at, now()) WHERE id = $1`, id)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to cancel event %v: %v", id, err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// RSVP upserts the users RSVP and keeps current_attendees and the waitlist in sync
+// the event row is locked FOR UPDATE so concurrent RSVPs to the same event run one after the other
+func (r *PostgresEventRepository) RSVP(ctx context.Context, eventID, userID uuid.UUID, status models.RSVPStatus) (*RSVPResult, error) {
+	result := &RSVPResult{Status: status}
+
+	err := utils.WithTx(ctx, r.db, func(tx pgx.Tx) error {
+		*result = RSVPResult{Status: status} // WithTx may run this again after a deadlock
+		event, err := scanEvent(utils.ExecuteQueryRowContext(ctx, tx, `SELECT `+eventColumns+` FROM public.events e WHERE e.id = $1 FOR UPDATE`, eventID))
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if event.IsCancelled() {
+			return ErrEventCancelled
+		}
+		result.Event = event
+
+		var previous models.RSVPStatus
+		err = utils.ExecuteQueryRowContext(ctx, tx,
+			`SELECT status FROM public.event_rsvps WHERE event_id = $1 AND user_id = $2`, eventID, userID,
+		).Scan(&previous)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return err
+		}
+		wasGoing := previous == models.RSVPGoing
+
+		// asking to go to a full event joins (or keeps your place on) the waitlist
+		if status == models.RSVPGoing && !wasGoing && event.IsFull() {
+			result.Status = models.RSVPWaitlisted
+			if previous == models.RSVPWaitlisted {
+				result.WaitlistPosition, err = waitlistRank(ctx, tx, eventID, userID)
+				return err
+			}
+		}
+
+		_, err = utils.ExecuteNonQueryContext(ctx, tx,
+			`INSERT INTO public.event_rsvps (event_id, user_id, status, waitlist_position)
+			 VALUES ($1, $2, $3, CASE WHEN $3 = 'waitlisted' THEN
+				(SELECT COALESCE(max(waitlist_position), 0) + 1 FROM public.event_rsvps WHERE event_id = $1)
+			 END)
+			 ON CONFLICT (event_id, user_id) DO UPDATE
+			 SET status = EXCLUDED.status, waitlist_position = EXCLUDED.waitlist_position`,
+			eventID, userID, result.Status,
+		)
+		if err != nil {
+			return err
+		}
+
+		willGo := result.Status == models.RSVPGoing
+		delta := 0
+		if willGo && !wasGoing {
+			delta = 1
+		} else if wasGoing && !willGo {
+			delta = -1
+		}
+		if delta != 0 {
+			err = utils.ExecuteQueryRowContext(ctx, tx,
+				`UPDATE public.events SET current_attendees = current_attendees + $2 WHERE id = $1 RETURNING current_attendees`,
+				eventID, delta,
+			).Scan(&event.CurrentAttendees)
+			if err != nil {
+				return err
+			}
+		}
+
+		// a going user dropping out frees a spot for the next person in line
+		if wasGoing && !willGo {
+			if result.Promoted, err = promoteWaitlisted(ctx, tx, event, 1); err != nil {
+				return err
+			}
+		}
+		if result.Status == models.RSVPWaitlisted {
+			result.WaitlistPosition, err = waitlistRank(ctx, tx, eventID, userID)
+		}
+		return err
+	})
+
+	switch {
+	case err == nil:
+		return result, nil
+	case errors.Is(err, ErrNotFound), errors.Is(err, ErrEventCancelled):
+		return nil, err
+	case pgErrorCode(err) == pgForeignKeyViolation:
+		return nil, ErrNotFound
+	case pgErrorCode(err) == pgCheckViolation:
+		// only possible if current_attendees was changed outside the api
+		return nil, ErrEventFull
+	}
+	logger.ErrorContext(ctx, "failed to rsvp user %v to event %v: %v", userID, eventID, err)
+	return nil, err
+}
+
+// ListAttendees returns the going and maybe RSVPs with their users, oldest first
+func (r *PostgresEventRepository) ListAttendees(ctx context.Context, eventID uuid.UUID, limit, offset int) ([]models.EventRSVP, int, error) {
+	limit, offset = clampPage(limit, offset)
+	event, err := r.GetByID(ctx, eventID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var total int
+	err = utils.ExecuteQueryRowContext(ctx, r.db,
+		`SELECT count(*) FROM public.event_rsvps WHERE event_id = $1 AND status IN ($2, $3)`,
+		eventID, models.RSVPGoing, models.RSVPMaybe,
+	).Scan(&total)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to count attendees of event %v: %v", eventID, err)
+		return nil, 0, err
+	}
+
+	rows, err := utils.ExecuteQueryContext(ctx, r.db,
+		`SELECT rs.status, rs.created_at, `+userColumns+`
+		 FROM public.event_rsvps rs
+		 JOIN `+userFrom+` ON p.id = rs.user_id
+		 WHERE rs.event_id = $1 AND rs.status IN ($2, $3)
+		 ORDER BY rs.created_at, rs.user_id
+		 LIMIT $4 OFFSET $5`,
+		eventID, models.RSVPGoing, models.RSVPMaybe, limit, offset,
+	)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to list attendees of event %v: %v", eventID, err)
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	attendees := []models.EventRSVP{}
+	for rows.Next() {
+		rsvp := models.EventRSVP{Event: *event}
+
+		dest := append([]interface{}{&rsvp.Statues, &rsvp.CreatedAt}, userDest(&rsvp.User)...)
+		if err := rows.Scan(dest...); err != nil {
+			logger.ErrorContext(ctx, "failed to scan attendee: %v", err)
+			return nil, 0, err
+		}
+		attendees = append(attendees, rsvp)
+	}
+	return attendees, total, rows.Err()
+}
+
+// ListWaitlist returns the waitlisted RSVPs with their users in promotion order
+func (r *PostgresEventRepository) ListWaitlist(ctx context.Context, eventID uuid.UUID) ([]models.EventRSVP, error) {
+	event, err := r.GetByID(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := utils.ExecuteQueryContext(ctx, r.db,
+		`SELECT rs.created_at, `+userColumns+`
+		 FROM public.event_rsvps rs
+		 JOIN `+userFrom+` ON p.id = rs.user_id
+		 WHERE rs.event_id = $1 AND rs.status = 'waitlisted'
+		 ORDER BY rs.waitlist_position`,
+		eventID,
+	)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to list waitlist of event %v: %v", eventID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	waitlist := []models.EventRSVP{}
+	for rows.Next() {
+		rsvp := models.EventRSVP{Event: *event, Statues: models.RSVPWaitlisted, WaitlistPosition: len(waitlist) + 1}
+
+		dest := append([]interface{}{&rsvp.CreatedAt}, userDest(&rsvp.User)...)
+		if err := rows.Scan(dest...); err != nil {
+			logger.ErrorContext(ctx, "failed to scan waitlisted user: %v", err)
+			return nil, err
+		}
+		waitlist = append(waitlist, rsvp)
+	}
+	return waitlist, rows.Err()
+}
+
+// ReorderWaitlist rewrites waitlist_position as 1..n in the order of userIDs
+func (r *PostgresEventRepository) ReorderWaitlist(ctx context.Context, eventID uuid.UUID, userIDs []uuid.UUID) error {
+	err := utils.WithTx(ctx, r.db, func(tx pgx.Tx) error {
+		var cancelledAt *time.Time
+		err := utils.ExecuteQueryRowContext(ctx, tx, `SELECT cancelled_at FROM public.events WHERE id = $1 FOR UPDATE`, eventID).Scan(&cancelledAt)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if cancelledAt != nil {
+			return ErrEventCancelled
+		}
+
+		var current []uuid.UUID
+		rows, err := utils.ExecuteQueryContext(ctx, tx, `SELECT user_id FROM public.event_rsvps WHERE event_id = $1 AND status = 'waitlisted'`, eventID)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			var id uuid.UUID
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return err
+			}
+			current = append(current, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if !sameIDs(current, userIDs) {
+			return ErrWaitlistMismatch
+		}
+
+		for i, id := range userIDs {
+			_, err := utils.ExecuteNonQueryContext(ctx, tx,
+				`UPDATE public.event_rsvps SET waitlist_position = $3 WHERE event_id = $1 AND user_id = $2`,
+				eventID, id, i+1,
+			)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	if err != nil && !errors.Is(err, ErrNotFound) && !errors.Is(err, ErrEventCancelled) && !errors.Is(err, ErrWaitlistMismatch) {
+		logger.ErrorContext(ctx, "failed to reorder waitlist of event %v: %v", eventID, err)
+	}
+	return err
+}
+
+// promoteWaitlisted moves up to n waitlisted users to going, in waitlist order, and bumps current_attendees
+// it must run in the transaction that locked the event row
+func promoteWaitlisted(ctx context.Context, tx pgx.Tx, event *models.Event, n int) ([]uuid.UUID, error) {
+	rows, err := utils.ExecuteQueryContext(ctx, tx,
+		`UPDATE public.event_rsvps SET status = 'going', waitlist_position = NULL
+		 WHERE event_id = $1 AND user_id IN (
+			SELECT user_id FROM public.event_rsvps
+			WHERE event_id = $1 AND status = 'waitlisted'
+			ORDER BY waitlist_position
+			LIMIT $2
+		 )
+		 RETURNING user_id`,
+		event.ID, n,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	promoted := []uuid.UUID{}
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		promoted = append(promoted, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil || len(promoted) == 0 {
+		return promoted, err
+	}
+
+	err = utils.ExecuteQueryRowContext(ctx, tx,
+		`UPDATE public.events SET current_attendees = current_attendees + $2 WHERE id = $1 RETURNING current_attendees`,
+		event.ID, len(promoted),
+	).Scan(&event.CurrentAttendees)
+	return promoted, err
+}
+
+// waitlistRank returns the 1 based place of the user in the waitlist
+func waitlistRank(ctx context.Context, tx pgx.Tx, eventID, userID uuid.UUID) (int, error) {
+	var rank int
+	err := utils.ExecuteQueryRowContext(ctx, tx,
+		`SELECT count(*) FROM public.event_rsvps
+		 WHERE event_id = $1 AND status = 'waitlisted'
+		 AND waitlist_position <= (SELECT waitlist_position FROM public.event_rsvps WHERE event_id = $1 AND user_id = $2)`,
+		eventID, userID,
+	).Scan(&rank)
+	return rank, err
+}
+
+// scanEvent reads one row selected with eventColumns into an event
+func scanEvent(row pgx.Row) (*models.Event, error) {
+	var event models.Event
+	err := row.Scan(
+		&event.ID, &event.CreatorID, &event.Title, &event.Description, &event.Location, &event.EventDate,
+		&event.MaxAttendees, &event.CurrentAttendees, &event.ImageURL, &event.CancelledAt,
+		&event.CreatedAt, &event.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
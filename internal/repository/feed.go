@@ -0,0 +1,20 @@
+// feed.go defines the FeedRepository interface for the home feeds
+// feeds use keyset pagination on (created_at, id) so new posts never shift the next page
+
+package repository
+
+import (
+	"context"
+	"feast-friends-api/internal/models"
+	"feast-friends-api/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+// FeedRepository describes the feed queries
+type FeedRepository interface {
+	// ListFollowing returns up to limit posts from users userID follows, newest first
+	// with the author attached. after is nil for the first page, otherwise only posts
+	// strictly older than the cursor are returned
+	ListFollowing(ctx context.Context, userID uuid.UUID, after *utils.Cursor, limit int) ([]models.PostWithUser, error)
+}
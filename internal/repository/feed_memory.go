@@ -0,0 +1,62 @@
+// feed_memory.go is an in-memory FeedRepository built on top of the in-memory posts and social repositories
+
+package repository
+
+import (
+	"context"
+	"feast-friends-api/internal/models"
+	"feast-friends-api/internal/utils"
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+// MemoryFeedRepository reads posts and follows from the other in-memory repositories
+type MemoryFeedRepository struct {
+	posts  *MemoryPostRepository
+	social *MemorySocialRepository
+}
+
+// make sure the implementation satisfies the interface at compile time
+var _ FeedRepository = (*MemoryFeedRepository)(nil)
+
+// NewMemoryFeedRepository creates a feed over the given in-memory posts and follows
+func NewMemoryFeedRepository(posts *MemoryPostRepository, social *MemorySocialRepository) *MemoryFeedRepository {
+	return &MemoryFeedRepository{posts: posts, social: social}
+}
+
+// ListFollowing returns the posts of the users userID follows, newest first
+func (r *MemoryFeedRepository) ListFollowing(ctx context.Context, userID uuid.UUID, after *utils.Cursor, limit int) ([]models.PostWithUser, error) {
+	limit = clampLimit(limit)
+	r.social.mu.Lock()
+	defer r.social.mu.Unlock()
+	r.posts.mu.RLock()
+	defer r.posts.mu.RUnlock()
+
+	feed := []models.PostWithUser{}
+	for _, post := range r.posts.posts {
+		if _, follows := r.social.follows[[2]uuid.UUID{userID, post.UserID}]; !follows {
+			continue
+		}
+		if after != nil && !olderThan(post, *after) {
+			continue
+		}
+		feed = append(feed, models.PostWithUser{Post: post, User: r.social.users[post.UserID]})
+	}
+
+	sort.Slice(feed, func(i, j int) bool {
+		return olderThan(feed[j].Post, utils.Cursor{CreatedAt: feed[i].CreatedAt, ID: feed[i].ID})
+	})
+	if len(feed) > limit {
+		feed = feed[:limit]
+	}
+	return feed, nil
+}
+
+// olderThan reports whether the post comes strictly after the cursor in (created_at, id) desc order
+func olderThan(post models.Post, c utils.Cursor) bool {
+	if !post.CreatedAt.Equal(c.CreatedAt) {
+		return post.CreatedAt.Before(c.CreatedAt)
+	}
+	return idLess(post.ID, c.ID)
+}
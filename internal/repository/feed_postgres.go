@@ -0,0 +1,69 @@
+// feed_postgres.go is the FeedRepository implementation backed by the pgx connection pool
+
+package repository
+
+import (
+	"context"
+	"feast-friends-api/internal/models"
+	"feast-friends-api/internal/utils"
+	"feast-friends-api/pkg/logger"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// PostgresFeedRepository builds feeds from public.posts and public.follows
+type PostgresFeedRepository struct {
+	db *pgxpool.Pool
+}
+
+// make sure the implementation satisfies the interface at compile time
+var _ FeedRepository = (*PostgresFeedRepository)(nil)
+
+// NewPostgresFeedRepository creates the repository using the given pool (usually utils.DB)
+func NewPostgresFeedRepository(db *pgxpool.Pool) *PostgresFeedRepository {
+	return &PostgresFeedRepository{db: db}
+}
+
+// ListFollowing returns the posts of the users userID follows, newest first
+// the row comparison (created_at, id) < (cursor) keeps the order stable when two posts share a timestamp
+func (r *PostgresFeedRepository) ListFollowing(ctx context.Context, userID uuid.UUID, after *utils.Cursor, limit int) ([]models.PostWithUser, error) {
+	limit = clampLimit(limit)
+	query := `SELECT ` + postColumns + `, ` + userColumns + `
+		FROM public.follows f
+		JOIN public.posts po ON po.user_id = f.following_id
+		JOIN ` + userFrom + ` ON p.id = po.user_id
+		WHERE f.follower_id = $1`
+	args := []interface{}{userID}
+
+	if after != nil {
+		query += ` AND (po.created_at, po.id) < ($2, $3)`
+		args = append(args, after.CreatedAt, after.ID)
+	}
+	query += ` ORDER BY po.created_at DESC, po.id DESC LIMIT ` + placeholder(len(args)+1)
+	args = append(args, limit)
+
+	rows, err := utils.ExecuteQueryContext(ctx, r.db, query, args...)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to load following feed for user %v: %v", userID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	feed := []models.PostWithUser{}
+	for rows.Next() {
+		var item models.PostWithUser
+		var recipe []byte
+
+		dest := append(postDest(&item.Post, &recipe), userDest(&item.User)...)
+		if err := rows.Scan(dest...); err != nil {
+			logger.ErrorContext(ctx, "failed to scan feed post: %v", err)
+			return nil, err
+		}
+		if err := decodeRecipe(&item.Post, recipe); err != nil {
+			return nil, err
+		}
+		feed = append(feed, item)
+	}
+	return feed, rows.Err()
+}
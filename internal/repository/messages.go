@@ -0,0 +1,41 @@
+// messages.go defines the MessageRepository interface for two-party conversations
+// a conversation stores its participants ordered (participant_1 < participant_2) so
+// opening a chat from either side always finds the same row
+
+package repository
+
+import (
+	"context"
+	"feast-friends-api/internal/models"
+	"feast-friends-api/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+// MessageRepository describes the operations on public.conversations and public.messages
+type MessageRepository interface {
+	// OpenConversation returns the conversation between the two users, creating it if needed
+	// created reports whether it was just created, ErrNotFound is returned if the other user does not exist
+	OpenConversation(ctx context.Context, userID, otherID uuid.UUID) (conversation *models.Conversation, created bool, err error)
+	// GetConversation returns ErrNotFound if the conversation does not exist
+	GetConversation(ctx context.Context, id uuid.UUID) (*models.Conversation, error)
+	// SendMessage inserts the message, fills in its id and created_at and bumps last_message_at
+	// ErrNotFound is returned when the conversation does not exist or the sender is not part of it
+	SendMessage(ctx context.Context, message *models.Message) error
+	// ListMessages returns up to limit messages of the conversation, newest first
+	// after is nil for the first page, otherwise only messages strictly older than the cursor are returned
+	ListMessages(ctx context.Context, conversationID uuid.UUID, after *utils.Cursor, limit int) ([]models.Message, error)
+	// MarkRead sets read_at on every unread message the other participant sent and returns how many were marked
+	MarkRead(ctx context.Context, conversationID, readerID uuid.UUID) (int, error)
+	// ListInbox returns a page of the users conversations with the other user, the last message
+	// and the unread count, most recent activity first, plus the total count
+	ListInbox(ctx context.Context, userID uuid.UUID, limit, offset int) ([]models.ConversationWithUser, int, error)
+}
+
+// orderedPair returns the two ids in the order the conversations table stores them
+func orderedPair(a, b uuid.UUID) (uuid.UUID, uuid.UUID) {
+	if idLess(a, b) {
+		return a, b
+	}
+	return b, a
+}
@@ -0,0 +1,187 @@
+// messages_memory.go is an in-memory MessageRepository used by tests and local experiments
+// it bumps last_message_at on send the same way the database trigger does
+
+package repository
+
+import (
+	"context"
+	"feast-friends-api/internal/models"
+	"feast-friends-api/internal/utils"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MemoryMessageRepository keeps conversations and messages in maps guarded by a mutex
+// the other user of a conversation is looked up in the users registered on the social repository
+type MemoryMessageRepository struct {
+	mu            sync.Mutex
+	social        *MemorySocialRepository
+	conversations map[uuid.UUID]models.Conversation
+	pairs         map[[2]uuid.UUID]uuid.UUID // ordered participant ids -> conversation id
+	messages      map[uuid.UUID][]models.Message // conversation id -> messages, oldest first
+}
+
+// make sure the implementation satisfies the interface at compile time
+var _ MessageRepository = (*MemoryMessageRepository)(nil)
+
+// NewMemoryMessageRepository creates an empty repository using the users registered on social
+func NewMemoryMessageRepository(social *MemorySocialRepository) *MemoryMessageRepository {
+	return &MemoryMessageRepository{
+		social:        social,
+		conversations: make(map[uuid.UUID]models.Conversation),
+		pairs:         make(map[[2]uuid.UUID]uuid.UUID),
+		messages:      make(map[uuid.UUID][]models.Message),
+	}
+}
+
+// OpenConversation returns the stored conversation for the pair or creates it
+func (r *MemoryMessageRepository) OpenConversation(ctx context.Context, userID, otherID uuid.UUID) (*models.Conversation, bool, error) {
+	if r.social.user(otherID).ID == uuid.Nil {
+		return nil, false, ErrNotFound
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	first, second := orderedPair(userID, otherID)
+	if id, ok := r.pairs[[2]uuid.UUID{first, second}]; ok {
+		conversation := r.conversations[id]
+		return &conversation, false, nil
+	}
+
+	now := time.Now().UTC()
+	conversation := models.Conversation{
+		ID:            uuid.New(),
+		User1ID:       first,
+		User2ID:       second,
+		LastMessageAt: now,
+		CreatedAt:     now,
+	}
+	r.conversations[conversation.ID] = conversation
+	r.pairs[[2]uuid.UUID{first, second}] = conversation.ID
+	return &conversation, true, nil
+}
+
+// GetConversation returns a copy of the conversation or ErrNotFound
+func (r *MemoryMessageRepository) GetConversation(ctx context.Context, id uuid.UUID) (*models.Conversation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	conversation, ok := r.conversations[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &conversation, nil
+}
+
+// SendMessage appends the message and bumps LastMessageAt
+func (r *MemoryMessageRepository) SendMessage(ctx context.Context, message *models.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	conversation, ok := r.conversations[message.ConversationID]
+	if !ok || !conversation.HasParticipant(message.SenderID) {
+		return ErrNotFound
+	}
+
+	message.ID = uuid.New()
+	message.ReadAt = nil
+	message.CreatedAt = time.Now().UTC()
+
+	r.messages[conversation.ID] = append(r.messages[conversation.ID], *message)
+	conversation.LastMessageAt = message.CreatedAt
+	r.conversations[conversation.ID] = conversation
+	return nil
+}
+
+// ListMessages returns the messages of the conversation, newest first
+func (r *MemoryMessageRepository) ListMessages(ctx context.Context, conversationID uuid.UUID, after *utils.Cursor, limit int) ([]models.Message, error) {
+	limit = clampLimit(limit)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored := r.messages[conversationID]
+	messages := []models.Message{}
+	for i := len(stored) - 1; i >= 0; i-- {
+		if after != nil && !messageOlderThan(stored[i], *after) {
+			continue
+		}
+		messages = append(messages, stored[i])
+		if len(messages) == limit {
+			break
+		}
+	}
+	return messages, nil
+}
+
+// MarkRead sets ReadAt on the unread messages sent by the other participant
+func (r *MemoryMessageRepository) MarkRead(ctx context.Context, conversationID, readerID uuid.UUID) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	conversation, ok := r.conversations[conversationID]
+	if !ok || !conversation.HasParticipant(readerID) {
+		return 0, nil
+	}
+
+	now := time.Now().UTC()
+	marked := 0
+	for i, m := range r.messages[conversationID] {
+		if m.SenderID != readerID && m.ReadAt == nil {
+			r.messages[conversationID][i].ReadAt = &now
+			marked++
+		}
+	}
+	return marked, nil
+}
+
+// ListInbox returns the users conversations, most recent activity first
+func (r *MemoryMessageRepository) ListInbox(ctx context.Context, userID uuid.UUID, limit, offset int) ([]models.ConversationWithUser, int, error) {
+	limit, offset = clampPage(limit, offset)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	inbox := []models.ConversationWithUser{}
+	for _, c := range r.conversations {
+		if !c.HasParticipant(userID) {
+			continue
+		}
+		item := models.ConversationWithUser{Conversation: c, OtherUser: r.social.user(c.OtherParticipant(userID))}
+		if stored := r.messages[c.ID]; len(stored) > 0 {
+			last := stored[len(stored)-1]
+			item.LastMessage = &last
+		}
+		for _, m := range r.messages[c.ID] {
+			if m.SenderID != userID && m.ReadAt == nil {
+				item.UnreadCount++
+			}
+		}
+		inbox = append(inbox, item)
+	}
+	sort.Slice(inbox, func(i, j int) bool {
+		if !inbox[i].LastMessageAt.Equal(inbox[j].LastMessageAt) {
+			return inbox[i].LastMessageAt.After(inbox[j].LastMessageAt)
+		}
+		return idLess(inbox[j].ID, inbox[i].ID)
+	})
+
+	total := len(inbox)
+	if offset >= total {
+		return []models.ConversationWithUser{}, total, nil
+	}
+	if offset+limit < total {
+		return inbox[offset : offset+limit], total, nil
+	}
+	return inbox[offset:], total, nil
+}
+
+// messageOlderThan reports whether the message comes strictly after the cursor in (created_at, id) desc order
+func messageOlderThan(message models.Message, c utils.Cursor) bool {
+	if !message.CreatedAt.Equal(c.CreatedAt) {
+		return message.CreatedAt.Before(c.CreatedAt)
+	}
+	return idLess(message.ID, c.ID)
+}
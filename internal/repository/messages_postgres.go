@@ -0,0 +1,257 @@
+// messages_postgres.go is the MessageRepository implementation backed by the pgx connection pool
+// last_message_at is bumped by the trigger in 007_direct_messages.sql when a message is inserted
+
+package repository
+
+import (
+	"context"
+	"errors"
+	"feast-friends-api/internal/models"
+	"feast-friends-api/internal/utils"
+	"feast-friends-api/pkg/logger"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// columns selected for every conversation query, the conversations table is always aliased as c
+const conversationColumns = `c.id, c.participant_1, c.participant_2, COALESCE(c.last_message_at, c.created_at), c.created_at`
+
+// columns selected for every message query, the messages table is always aliased as m
+const messageColumns = `m.id, m.conversation_id, m.sender_id, m.content, m.read_at, m.message_type, m.created_at`
+
+// PostgresMessageRepository reads and writes conversations and messages
+type PostgresMessageRepository struct {
+	db *pgxpool.Pool
+}
+
+// make sure the implementation satisfies the interface at compile time
+var _ MessageRepository = (*PostgresMessageRepository)(nil)
+
+// NewPostgresMessageRepository creates the repository using the given pool (usually utils.DB)
+func NewPostgresMessageRepository(db *pgxpool.Pool) *PostgresMessageRepository {
+	return &PostgresMessageRepository{db: db}
+}
+
+// OpenConversation inserts the ordered pair if it does not exist yet and returns the stored conversation
+func (r *PostgresMessageRepository) OpenConversation(ctx context.Context, userID, otherID uuid.UUID) (*models.Conversation, bool, error) {
+	first, second := orderedPair(userID, otherID)
+
+	tag, err := utils.ExecuteNonQueryContext(ctx, r.db,
+		`INSERT INTO public.conversations (participant_1, participant_2) VALUES ($1, $2)
+		 ON CONFLICT (participant_1, participant_2) DO NOTHING`,
+		first, second,
+	)
+	if pgErrorCode(err) == pgForeignKeyViolation {
+		return nil, false, ErrNotFound
+	}
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to open conversation between %v and %v: %v", first, second, err)
+		return nil, false, err
+	}
+
+	conversation, err := scanConversation(utils.ExecuteQueryRowContext(ctx, r.db,
+		`SELECT `+conversationColumns+` FROM public.conversations c WHERE c.participant_1 = $1 AND c.participant_2 = $2`,
+		first, second,
+	))
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to load conversation between %v and %v: %v", first, second, err)
+		return nil, false, err
+	}
+	return conversation, tag.RowsAffected() == 1, nil
+}
+
+// GetConversation returns the conversation with the given id or ErrNotFound
+func (r *PostgresMessageRepository) GetConversation(ctx context.Context, id uuid.UUID) (*models.Conversation, error) {
+	conversation, err := scanConversation(utils.ExecuteQueryRowContext(ctx, r.db, `SELECT `+conversationColumns+` FROM public.conversations c WHERE c.id = $1`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to get conversation %v: %v", id, err)
+		return nil, err
+	}
+	return conversation, nil
+}
+
+// SendMessage inserts the message only if the sender is one of the participants
+func (r *PostgresMessageRepository) SendMessage(ctx context.Context, message *models.Message) error {
+	err := utils.ExecuteQueryRowContext(ctx, r.db,
+		`INSERT INTO public.messages (conversation_id, sender_id, content, message_type)
+		 SELECT c.id, $2, $3, $4 FROM public.conversations c
+		 WHERE c.id = $1 AND $2 IN (c.participant_1, c.participant_2)
+		 RETURNING id, created_at`,
+		message.ConversationID, message.SenderID, message.Content, message.MessageType,
+	).Scan(&message.ID, &message.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to send message to conversation %v: %v", message.ConversationID, err)
+		return err
+	}
+	message.ReadAt = nil
+	return nil
+}
+
+// ListMessages returns the messages of the conversation, newest first
+// the row comparison (created_at, id) < (cursor) keeps the order stable when two messages share a timestamp
+func (r *PostgresMessageRepository) ListMessages(ctx context.Context, conversationID uuid.UUID, after *utils.Cursor, limit int) ([]models.Message, error) {
+	limit = clampLimit(limit)
+	query := `SELECT ` + messageColumns + ` FROM public.messages m WHERE m.conversation_id = $1`
+	args := []interface{}{conversationID}
+
+	if after != nil {
+		query += ` AND (m.created_at, m.id) < ($2, $3)`
+		args = append(args, after.CreatedAt, after.ID)
+	}
+	query += ` ORDER BY m.created_at DESC, m.id DESC LIMIT ` + placeholder(len(args)+1)
+	args = append(args, limit)
+
+	rows, err := utils.ExecuteQueryContext(ctx, r.db, query, args...)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to list messages of conversation %v: %v", conversationID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := []models.Message{}
+	for rows.Next() {
+		message, err := scanMessage(rows)
+		if err != nil {
+			logger.ErrorContext(ctx, "failed to scan message: %v", err)
+			return nil, err
+		}
+		messages = append(messages, *message)
+	}
+	return messages, rows.Err()
+}
+
+// MarkRead sets read_at on the unread messages sent by the other participant
+func (r *PostgresMessageRepository) MarkRead(ctx context.Context, conversationID, readerID uuid.UUID) (int, error) {
+	tag, err := utils.ExecuteNonQueryContext(ctx, r.db,
+		`UPDATE public.messages m SET read_at = now()
+		 FROM public.conversations c
+		 WHERE c.id = m.conversation_id AND m.conversation_id = $1
+		 AND $2 IN (c.participant_1, c.participant_2)
+		 AND m.sender_id <> $2 AND m.read_at IS NULL`,
+		conversationID, readerID,
+	)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to mark conversation %v read for user %v: %v", conversationID, readerID, err)
+		return 0, err
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+// ListInbox returns the users conversations, most recent activity first
+// the last message comes from a LATERAL join so each conversation costs a single index lookup
+func (r *PostgresMessageRepository) ListInbox(ctx context.Context, userID uuid.UUID, limit, offset int) ([]models.ConversationWithUser, int, error) {
+	limit, offset = clampPage(limit, offset)
+	var total int
+	err := utils.ExecuteQueryRowContext(ctx, r.db,
+		`SELECT count(*) FROM public.conversations c WHERE $1 IN (c.participant_1, c.participant_2)`, userID,
+	).Scan(&total)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to count conversations of user %v: %v", userID, err)
+		return nil, 0, err
+	}
+
+	rows, err := utils.ExecuteQueryContext(ctx, r.db,
+		`SELECT `+conversationColumns+`, `+userColumns+`,
+			lm.id, lm.conversation_id, lm.sender_id, lm.content, lm.read_at, lm.message_type, lm.created_at,
+			(SELECT count(*) FROM public.messages um
+			 WHERE um.conversation_id = c.id AND um.sender_id <> $1 AND um.read_at IS NULL)
+		 FROM public.conversations c
+		 JOIN `+userFrom+` ON p.id = CASE WHEN c.participant_1 = $1 THEN c.participant_2 ELSE c.participant_1 END
+		 LEFT JOIN LATERAL (
+			SELECT `+messageColumns+` FROM public.messages m
+			WHERE m.conversation_id = c.id
+			ORDER BY m.created_at DESC, m.id DESC
+			LIMIT 1
+		 ) lm ON true
+		 WHERE $1 IN (c.participant_1, c.participant_2)
+		 ORDER BY COALESCE(c.last_message_at, c.created_at) DESC, c.id DESC
+		 LIMIT $2 OFFSET $3`,
+		userID, limit, offset,
+	)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to list conversations of user %v: %v", userID, err)
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	inbox := []models.ConversationWithUser{}
+	for rows.Next() {
+		var item models.ConversationWithUser
+		var last nullableMessage
+
+		dest := append(conversationDest(&item.Conversation), userDest(&item.OtherUser)...)
+		dest = append(dest, last.dest()...)
+		dest = append(dest, &item.UnreadCount)
+		if err := rows.Scan(dest...); err != nil {
+			logger.ErrorContext(ctx, "failed to scan conversation: %v", err)
+			return nil, 0, err
+		}
+		item.LastMessage = last.message()
+		inbox = append(inbox, item)
+	}
+	return inbox, total, rows.Err()
+}
+
+// scanConversation reads one row selected with conversationColumns into a conversation
+func scanConversation(row pgx.Row) (*models.Conversation, error) {
+	var conversation models.Conversation
+	if err := row.Scan(conversationDest(&conversation)...); err != nil {
+		return nil, err
+	}
+	return &conversation, nil
+}
+
+// conversationDest returns the scan destinations matching conversationColumns
+func conversationDest(c *models.Conversation) []interface{} {
+	return []interface{}{&c.ID, &c.User1ID, &c.User2ID, &c.LastMessageAt, &c.CreatedAt}
+}
+
+// scanMessage reads one row selected with messageColumns into a message
+func scanMessage(row pgx.Row) (*models.Message, error) {
+	var message models.Message
+	err := row.Scan(
+		&message.ID, &message.ConversationID, &message.SenderID, &message.Content,
+		&message.ReadAt, &message.MessageType, &message.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
+// nullableMessage receives the messageColumns of a LEFT JOIN that may not match any message
+type nullableMessage struct {
+	id, conversationID, senderID *uuid.UUID
+	content, messageType         *string
+	readAt, createdAt            *time.Time
+}
+
+// dest returns the scan destinations in messageColumns order
+func (n *nullableMessage) dest() []interface{} {
+	return []interface{}{&n.id, &n.conversationID, &n.senderID, &n.content, &n.readAt, &n.messageType, &n.createdAt}
+}
+
+// message returns the scanned message or nil when the join did not match
+func (n *nullableMessage) message() *models.Message {
+	if n.id == nil {
+		return nil
+	}
+	return &models.Message{
+		ID:             *n.id,
+		ConversationID: *n.conversationID,
+		SenderID:       *n.senderID,
+		Content:        *n.content,
+		ReadAt:         n.readAt,
+		MessageType:    *n.messageType,
+		CreatedAt:      *n.createdAt,
+	}
+}
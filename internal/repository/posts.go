@@ -0,0 +1,27 @@
+// posts.go defines the PostRepository interface used by handlers to read and write posts
+// the recipe column is stored as JSONB and mapped to models.Recipe by the implementations
+
+package repository
+
+import (
+	"context"
+	"feast-friends-api/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// PostRepository describes every operation we can run against public.posts
+type PostRepository interface {
+	// Create inserts the post and fills in its generated ID and CreatedAt
+	Create(ctx context.Context, post *models.Post) error
+	// GetByID returns ErrNotFound if the post does not exist
+	GetByID(ctx context.Context, id uuid.UUID) (*models.Post, error)
+	// ListByUser returns a page of a users posts, newest first, plus the total count
+	ListByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]models.Post, int, error)
+	// Update saves the editable fields (title, description, image, recipe)
+	Update(ctx context.Context, post *models.Post) error
+	// Delete returns ErrNotFound if the post does not exist
+	Delete(ctx context.Context, id uuid.UUID) error
+	// ListFeed returns a page of every post, newest first, plus the total count
+	ListFeed(ctx context.Context, limit, offset int) ([]models.Post, int, error)
+}
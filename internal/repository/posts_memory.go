@@ -0,0 +1,128 @@
+// posts_memory.go is an in-memory PostRepository used by tests and local experiments
+// it behaves like the postgres implementation (ordering, not found errors) without a database
+
+package repository
+
+import (
+	"context"
+	"feast-friends-api/internal/models"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MemoryPostRepository keeps posts in a map guarded by a mutex
+type MemoryPostRepository struct {
+	mu     sync.RWMutex
+	posts  map[uuid.UUID]models.Post
+}
+
+// make sure the implementation satisfies the interface at compile time
+var _ PostRepository = (*MemoryPostRepository)(nil)
+
+// NewMemoryPostRepository creates an empty in-memory repository
+func NewMemoryPostRepository() *MemoryPostRepository {
+	return &MemoryPostRepository{posts: make(map[uuid.UUID]models.Post)}
+}
+
+// Create stores a copy of the post and fills in its id and created_at
+func (r *MemoryPostRepository) Create(ctx context.Context, post *models.Post) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	post.ID = uuid.New()
+	post.LikesCount = 0
+	post.CommentsCount = 0
+	post.CreatedAt = time.Now().UTC()
+
+	r.posts[post.ID] = *post
+	return nil
+}
+
+// GetByID returns a copy of the post or ErrNotFound
+func (r *MemoryPostRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Post, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	post, ok := r.posts[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &post, nil
+}
+
+// ListByUser returns a page of the users posts, newest first
+func (r *MemoryPostRepository) ListByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]models.Post, int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.page(func(p models.Post) bool { return p.UserID == userID }, limit, offset)
+}
+
+// Update overwrites the editable fields of the stored post
+func (r *MemoryPostRepository) Update(ctx context.Context, post *models.Post) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.posts[post.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	stored.Title = post.Title
+	stored.Description = post.Description
+	stored.ImageURL = post.ImageURL
+	stored.Recipe = post.Recipe
+	r.posts[post.ID] = stored
+	return nil
+}
+
+// Delete removes the post or returns ErrNotFound
+func (r *MemoryPostRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.posts[id]; !ok {
+		return ErrNotFound
+	}
+	delete(r.posts, id)
+	return nil
+}
+
+// ListFeed returns a page of every post, newest first
+func (r *MemoryPostRepository) ListFeed(ctx context.Context, limit, offset int) ([]models.Post, int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.page(func(models.Post) bool { return true }, limit, offset)
+}
+
+// page filters, sorts (created_at desc, id desc) and slices the stored posts
+// callers must hold the read lock
+func (r *MemoryPostRepository) page(keep func(models.Post) bool, limit, offset int) ([]models.Post, int, error) {
+	limit, offset = clampPage(limit, offset)
+	matched := []models.Post{}
+	for _, p := range r.posts {
+		if keep(p) {
+			matched = append(matched, p)
+		}
+	}
+
+	sort.Slice(matched, func(i, j int) bool {
+		if !matched[i].CreatedAt.Equal(matched[j].CreatedAt) {
+			return matched[i].CreatedAt.After(matched[j].CreatedAt)
+		}
+		return idLess(matched[j].ID, matched[i].ID)
+	})
+
+	total := len(matched)
+	if offset >= total {
+		return []models.Post{}, total, nil
+	}
+	end := total
+	if offset+limit < total {
+		end = offset + limit
+	}
+	return matched[offset:end], total, nil
+}
@@ -0,0 +1,206 @@
+// posts_postgres.go is the PostRepository implementation backed by the pgx connection pool
+
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"feast-friends-api/internal/models"
+	"feast-friends-api/internal/utils"
+	"feast-friends-api/pkg/logger"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// columns selected for every post query, kept in the same order as scanPost
+// the posts table is always aliased as po so the columns can be joined with userColumns
+const postColumns = `po.id, po.user_id, po.title, COALESCE(po.description, ''), po.image_url, po.recipe,
+	po.likes_count, po.comments_count, po.created_at`
+
+// PostgresPostRepository reads and writes posts in public.posts
+type PostgresPostRepository struct {
+	db *pgxpool.Pool
+}
+
+// make sure the implementation satisfies the interface at compile time
+var _ PostRepository = (*PostgresPostRepository)(nil)
+
+// NewPostgresPostRepository creates the repository using the given pool (usually utils.DB)
+func NewPostgresPostRepository(db *pgxpool.Pool) *PostgresPostRepository {
+	return &PostgresPostRepository{db: db}
+}
+
+// Create inserts the post and fills in the generated id and created_at
+func (r *PostgresPostRepository) Create(ctx context.Context, post *models.Post) error {
+	recipe, err := json.Marshal(post.Recipe)
+	if err != nil {
+		return fmt.Errorf("failed to encode recipe: %w", err)
+	}
+
+	err = utils.ExecuteQueryRowContext(ctx, r.db,
+		`INSERT INTO public.posts (user_id, title, description, image_url, recipe)
+		 VALUES ($1, $2, $3, $4, $5)
+		 RETURNING id, likes_count, comments_count, created_at`,
+		post.UserID, post.Title, post.Description, post.ImageURL, recipe,
+	).Scan(&post.ID, &post.LikesCount, &post.CommentsCount, &post.CreatedAt)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to create post: %v", err)
+		return err
+	}
+	return nil
+}
+
+// GetByID returns the post with the given id or ErrNotFound
+func (r *PostgresPostRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Post, error) {
+	row := utils.ExecuteQueryRowContext(ctx, r.db, `SELECT `+postColumns+` FROM public.posts po WHERE po.id = $1`, id)
+
+	post, err := scanPost(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to get post %v: %v", id, err)
+		return nil, err
+	}
+	return post, nil
+}
+
+// ListByUser returns a page of posts created by the user, newest first
+func (r *PostgresPostRepository) ListByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]models.Post, int, error) {
+	limit, offset = clampPage(limit, offset)
+	var total int
+	if err := utils.ExecuteQueryRowContext(ctx, r.db, `SELECT count(*) FROM public.posts WHERE user_id = $1`, userID).Scan(&total); err != nil {
+		logger.ErrorContext(ctx, "failed to count posts for user %v: %v", userID, err)
+		return nil, 0, err
+	}
+
+	rows, err := utils.ExecuteQueryContext(ctx, r.db,
+		`SELECT `+postColumns+` FROM public.posts po
+		 WHERE po.user_id = $1
+		 ORDER BY po.created_at DESC, po.id DESC
+		 LIMIT $2 OFFSET $3`,
+		userID, limit, offset,
+	)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to list posts for user %v: %v", userID, err)
+		return nil, 0, err
+	}
+
+	posts, err := collectPosts(rows)
+	return posts, total, err
+}
+
+// Update saves the editable fields of the post
+func (r *PostgresPostRepository) Update(ctx context.Context, post *models.Post) error {
+	recipe, err := json.Marshal(post.Recipe)
+	if err != nil {
+		return fmt.Errorf("failed to encode recipe: %w", err)
+	}
+
+	tag, err := utils.ExecuteNonQueryContext(ctx, r.db,
+		`UPDATE public.posts
+		 SET title = $2, description = $3, image_url = $4, recipe = $5
+		 WHERE id = $1`,
+		post.ID, post.Title, post.Description, post.ImageURL, recipe,
+	)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to update post %v: %v", post.ID, err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Delete removes the post, likes and comments are removed by the ON DELETE CASCADE
+func (r *PostgresPostRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	tag, err := utils.ExecuteNonQueryContext(ctx, r.db, `DELETE FROM public.posts WHERE id = $1`, id)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to delete post %v: %v", id, err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ListFeed returns a page of every post, newest first
+func (r *PostgresPostRepository) ListFeed(ctx context.Context, limit, offset int) ([]models.Post, int, error) {
+	limit, offset = clampPage(limit, offset)
+	var total int
+	if err := utils.ExecuteQueryRowContext(ctx, r.db, `SELECT count(*) FROM public.posts`).Scan(&total); err != nil {
+		logger.ErrorContext(ctx, "failed to count posts: %v", err)
+		return nil, 0, err
+	}
+
+	rows, err := utils.ExecuteQueryContext(ctx, r.db,
+		`SELECT `+postColumns+` FROM public.posts po
+		 ORDER BY po.created_at DESC, po.id DESC
+		 LIMIT $1 OFFSET $2`,
+		limit, offset,
+	)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to list feed: %v", err)
+		return nil, 0, err
+	}
+
+	posts, err := collectPosts(rows)
+	return posts, total, err
+}
+
+// scanPost reads one row selected with postColumns into a post
+// the recipe JSONB column is decoded into models.Recipe, a NULL recipe is left empty
+func scanPost(row pgx.Row) (*models.Post, error) {
+	var post models.Post
+	var recipe []byte
+
+	if err := row.Scan(postDest(&post, &recipe)...); err != nil {
+		return nil, err
+	}
+	if err := decodeRecipe(&post, recipe); err != nil {
+		return nil, err
+	}
+	return &post, nil
+}
+
+// postDest returns the scan destinations matching postColumns
+// the raw recipe JSON is scanned into recipe and decoded afterwards with decodeRecipe
+func postDest(post *models.Post, recipe *[]byte) []interface{} {
+	return []interface{}{
+		&post.ID, &post.UserID, &post.Title, &post.Description, &post.ImageURL,
+		recipe, &post.LikesCount, &post.CommentsCount, &post.CreatedAt,
+	}
+}
+
+// decodeRecipe decodes the recipe JSONB into the post, a NULL recipe is left empty
+func decodeRecipe(post *models.Post, recipe []byte) error {
+	if len(recipe) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(recipe, &post.Recipe); err != nil {
+		return fmt.Errorf("failed to decode recipe of post %v: %w", post.ID, err)
+	}
+	return nil
+}
+
+// collectPosts scans every row and closes rows when done
+func collectPosts(rows pgx.Rows) ([]models.Post, error) {
+	defer rows.Close()
+
+	posts := []models.Post{}
+	for rows.Next() {
+		post, err := scanPost(rows)
+		if err != nil {
+			logger.Error("failed to scan post: %v", err)
+			return nil, err
+		}
+		posts = append(posts, *post)
+	}
+	return posts, rows.Err()
+}
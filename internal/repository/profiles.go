@@ -0,0 +1,31 @@
+// profiles.go defines the ProfileRepository used when users sign up and log in
+// profiles are the public side of auth.users, they share the same id
+
+package repository
+
+import (
+	"context"
+	"errors"
+	"feast-friends-api/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// ErrUsernameTaken is returned when another profile already uses the username
+var ErrUsernameTaken = errors.New("username already taken")
+
+// ProfileRepository describes the operations on public.profiles
+type ProfileRepository interface {
+	// Create inserts the profile of an auth user and fills in the stored fields,
+	// returns ErrUsernameTaken when the username is used (or the user already has a profile)
+	// and ErrNotFound when the auth user does not exist
+	Create(ctx context.Context, user *models.User) error
+	// GetByID returns the user or ErrNotFound
+	GetByID(ctx context.Context, id uuid.UUID) (*models.User, error)
+	// UsernameTaken reports whether a profile already uses the username, ignoring case
+	UsernameTaken(ctx context.Context, username string) (bool, error)
+	// Roles returns the roles of the user or ErrNotFound
+	Roles(ctx context.Context, id uuid.UUID) ([]string, error)
+	// SetRoles replaces the roles of the user, returns ErrNotFound
+	SetRoles(ctx context.Context, id uuid.UUID, roles []string) error
+}
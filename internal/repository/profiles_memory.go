@@ -0,0 +1,97 @@
+// profiles_memory.go is an in-memory ProfileRepository used by tests and local experiments
+// profiles are stored with the users of the MemorySocialRepository so follows and likes see them
+
+package repository
+
+import (
+	"context"
+	"feast-friends-api/internal/models"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MemoryProfileRepository reads and writes the users of a MemorySocialRepository
+type MemoryProfileRepository struct {
+	social *MemorySocialRepository
+	roles  map[uuid.UUID][]string // guarded by social.mu, users missing here only have the user role
+}
+
+// make sure the implementation satisfies the interface at compile time
+var _ ProfileRepository = (*MemoryProfileRepository)(nil)
+
+// NewMemoryProfileRepository creates the repository on top of the users known by social
+func NewMemoryProfileRepository(social *MemorySocialRepository) *MemoryProfileRepository {
+	return &MemoryProfileRepository{social: social, roles: make(map[uuid.UUID][]string)}
+}
+
+// Create stores the profile unless the username is taken
+func (r *MemoryProfileRepository) Create(ctx context.Context, user *models.User) error {
+	r.social.mu.Lock()
+	defer r.social.mu.Unlock()
+
+	for _, existing := range r.social.users {
+		if strings.EqualFold(existing.Username, user.Username) {
+			return ErrUsernameTaken
+		}
+	}
+	if _, ok := r.social.users[user.ID]; ok {
+		return ErrUsernameTaken
+	}
+
+	user.CreatedAt = time.Now().UTC()
+	r.social.users[user.ID] = *user
+	return nil
+}
+
+// GetByID returns the stored user
+func (r *MemoryProfileRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	r.social.mu.Lock()
+	defer r.social.mu.Unlock()
+
+	user, ok := r.social.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &user, nil
+}
+
+// UsernameTaken compares the usernames case insensitively
+func (r *MemoryProfileRepository) UsernameTaken(ctx context.Context, username string) (bool, error) {
+	r.social.mu.Lock()
+	defer r.social.mu.Unlock()
+
+	for _, existing := range r.social.users {
+		if strings.EqualFold(existing.Username, username) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Roles returns the stored roles, "user" for profiles that were never given other roles
+func (r *MemoryProfileRepository) Roles(ctx context.Context, id uuid.UUID) ([]string, error) {
+	r.social.mu.Lock()
+	defer r.social.mu.Unlock()
+
+	if _, ok := r.social.users[id]; !ok {
+		return nil, ErrNotFound
+	}
+	if roles, ok := r.roles[id]; ok {
+		return append([]string(nil), roles...), nil
+	}
+	return []string{"user"}, nil
+}
+
+// SetRoles stores a copy of the roles
+func (r *MemoryProfileRepository) SetRoles(ctx context.Context, id uuid.UUID, roles []string) error {
+	r.social.mu.Lock()
+	defer r.social.mu.Unlock()
+
+	if _, ok := r.social.users[id]; !ok {
+		return ErrNotFound
+	}
+	r.roles[id] = append([]string(nil), roles...)
+	return nil
+}
@@ -0,0 +1,106 @@
+// profiles_postgres.go is the ProfileRepository implementation backed by the pgx connection pool
+
+package repository
+
+import (
+	"context"
+	"errors"
+	"feast-friends-api/internal/models"
+	"feast-friends-api/internal/utils"
+	"feast-friends-api/pkg/logger"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// PostgresProfileRepository reads and writes public.profiles
+type PostgresProfileRepository struct {
+	db *pgxpool.Pool
+}
+
+// make sure the implementation satisfies the interface at compile time
+var _ ProfileRepository = (*PostgresProfileRepository)(nil)
+
+// NewPostgresProfileRepository creates the repository using the given pool (usually utils.DB)
+func NewPostgresProfileRepository(db *pgxpool.Pool) *PostgresProfileRepository {
+	return &PostgresProfileRepository{db: db}
+}
+
+// Create inserts the profile and reloads it so the email and counters are filled in
+func (r *PostgresProfileRepository) Create(ctx context.Context, user *models.User) error {
+	_, err := utils.ExecuteNonQueryContext(ctx, r.db,
+		`INSERT INTO public.profiles (id, username, full_name) VALUES ($1, $2, NULLIF($3, ''))`,
+		user.ID, user.Username, user.FullName,
+	)
+	switch pgErrorCode(err) {
+	case pgUniqueViolation:
+		return ErrUsernameTaken
+	case pgForeignKeyViolation:
+		return ErrNotFound
+	}
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to create profile for user %v: %v", user.ID, err)
+		return err
+	}
+
+	stored, err := r.GetByID(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	*user = *stored
+	return nil
+}
+
+// GetByID returns the user with the given id or ErrNotFound
+func (r *PostgresProfileRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	user, err := scanUser(utils.ExecuteQueryRowContext(ctx, r.db, `SELECT `+userColumns+` FROM `+userFrom+` WHERE p.id = $1`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to get profile %v: %v", id, err)
+		return nil, err
+	}
+	return user, nil
+}
+
+// UsernameTaken compares the usernames case insensitively
+func (r *PostgresProfileRepository) UsernameTaken(ctx context.Context, username string) (bool, error) {
+	var taken bool
+	err := utils.ExecuteQueryRowContext(ctx, r.db,
+		`SELECT EXISTS (SELECT 1 FROM public.profiles WHERE lower(username) = lower($1))`, username,
+	).Scan(&taken)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to check username %q: %v", username, err)
+		return false, err
+	}
+	return taken, nil
+}
+
+// Roles reads profiles.roles (009_profile_roles.sql)
+func (r *PostgresProfileRepository) Roles(ctx context.Context, id uuid.UUID) ([]string, error) {
+	var roles []string
+	err := utils.ExecuteQueryRowContext(ctx, r.db, `SELECT roles FROM public.profiles WHERE id = $1`, id).Scan(&roles)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to get roles of user %v: %v", id, err)
+		return nil, err
+	}
+	return roles, nil
+}
+
+// SetRoles replaces profiles.roles, the check constraint rejects unknown roles
+func (r *PostgresProfileRepository) SetRoles(ctx context.Context, id uuid.UUID, roles []string) error {
+	tag, err := utils.ExecuteNonQueryContext(ctx, r.db, `UPDATE public.profiles SET roles = $2 WHERE id = $1`, id, roles)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to set roles of user %v: %v", id, err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
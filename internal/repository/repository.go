@@ -0,0 +1,85 @@
+// Package repository contains the data access layer of the api.
+// each resource has an interface describing what handlers can do with it,
+// a postgres implementation backed by the pgx pool and an in-memory one for tests
+package repository
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgconn"
+)
+
+// ErrNotFound is returned when the requested row does not exist
+var ErrNotFound = errors.New("record not found")
+
+// ErrInvalidRelation is returned when a row would reference itself or break a CHECK constraint
+var ErrInvalidRelation = errors.New("invalid relation")
+
+// postgres error codes we translate into repository errors
+const (
+	pgUniqueViolation     = "23505"
+	pgForeignKeyViolation = "23503"
+	pgCheckViolation      = "23514"
+)
+
+// page sizes shared by the lists of both implementations so the in-memory repositories page like postgres
+const (
+	// defaultListLimit is used when a list is asked for a limit <= 0
+	defaultListLimit = 20
+	// maxListLimit caps one list call, handlers cap pages lower and may ask for one extra row to see if
+	// there is a next page
+	maxListLimit = 500
+)
+
+// clampLimit applies defaultListLimit and maxListLimit to limit
+func clampLimit(limit int) int {
+	if limit <= 0 {
+		return defaultListLimit
+	}
+	return min(limit, maxListLimit)
+}
+
+// clampPage is clampLimit for offset paginated lists, a negative offset becomes 0
+func clampPage(limit, offset int) (int, int) {
+	return clampLimit(limit), max(offset, 0)
+}
+
+// pgErrorCode returns the SQLSTATE code of a postgres error, or "" for any other error
+func pgErrorCode(err error) string {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code
+	}
+	return ""
+}
+
+// placeholder returns the postgres positional parameter for n (e.g. $3)
+func placeholder(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+
+// sameIDs reports whether both slices hold the same ids, ignoring order
+func sameIDs(a, b []uuid.UUID) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	seen := make(map[uuid.UUID]int, len(a))
+	for _, id := range a {
+		seen[id]++
+	}
+	for _, id := range b {
+		if seen[id] == 0 {
+			return false
+		}
+		seen[id]--
+	}
+	return true
+}
+
+// idLess orders ids byte by byte, the same order postgres uses for uuid columns
+func idLess(a, b uuid.UUID) bool {
+	return bytes.Compare(a[:], b[:]) < 0
+}
@@ -0,0 +1,50 @@
+// sessions.go defines the SessionRepository used by the token service in internal/auth
+// a session is a refresh token family: every refresh replaces the current token of the family,
+// presenting a token that was already replaced means it leaked, so the whole family is revoked.
+// it also keeps the revocation list of access tokens that were logged out before they expire
+
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrSessionRevoked is returned when refreshing a family that was revoked or has expired
+var ErrSessionRevoked = errors.New("session revoked")
+
+// ErrTokenReused is returned when a refresh token that was already rotated is presented again,
+// the family is revoked before the error is returned
+var ErrTokenReused = errors.New("refresh token reused")
+
+// Session is one refresh token family
+type Session struct {
+	ID         uuid.UUID
+	UserID     uuid.UUID
+	CurrentJTI uuid.UUID // id of the only refresh token of the family that can still be used
+	ExpiresAt  time.Time
+	RevokedAt  *time.Time
+	CreatedAt  time.Time
+}
+
+// SessionRepository describes the operations on public.auth_sessions and public.revoked_tokens
+type SessionRepository interface {
+	// CreateSession stores a new family, CreatedAt is set by the implementation
+	CreateSession(ctx context.Context, session *Session) error
+	// RotateSession makes newJTI the current token of the family if oldJTI is the current one,
+	// the family keeps its expiry so a session cannot be stretched forever by refreshing
+	// returns ErrNotFound for unknown families, ErrSessionRevoked for revoked or expired ones
+	// and ErrTokenReused (after revoking the family) when oldJTI was already rotated
+	RotateSession(ctx context.Context, id, oldJTI, newJTI uuid.UUID) error
+	// RevokeSession revokes the family, revoking it twice is not an error
+	RevokeSession(ctx context.Context, id uuid.UUID) error
+	// RevokeUserSessions revokes every family of the user, e.g. after a password change
+	RevokeUserSessions(ctx context.Context, userID uuid.UUID) error
+	// RevokeToken adds the token id to the revocation list until expiresAt
+	RevokeToken(ctx context.Context, tokenID string, expiresAt time.Time) error
+	// IsTokenRevoked reports whether the token id is on the revocation list
+	IsTokenRevoked(ctx context.Context, tokenID string) (bool, error)
+}
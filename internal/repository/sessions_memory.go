@@ -0,0 +1,120 @@
+// sessions_memory.go is an in-memory SessionRepository used by tests and local experiments
+// expired revocations are swept whenever a token is revoked, like the postgres implementation does
+
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MemorySessionRepository keeps the sessions and the revocation list in memory
+type MemorySessionRepository struct {
+	mu       sync.Mutex
+	sessions map[uuid.UUID]Session
+	revoked  map[string]time.Time // token id -> when the token expires anyway
+}
+
+// make sure the implementation satisfies the interface at compile time
+var _ SessionRepository = (*MemorySessionRepository)(nil)
+
+// NewMemorySessionRepository creates an empty repository
+func NewMemorySessionRepository() *MemorySessionRepository {
+	return &MemorySessionRepository{
+		sessions: make(map[uuid.UUID]Session),
+		revoked:  make(map[string]time.Time),
+	}
+}
+
+// CreateSession stores the family
+func (r *MemorySessionRepository) CreateSession(ctx context.Context, session *Session) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	session.CreatedAt = time.Now().UTC()
+	session.RevokedAt = nil
+	r.sessions[session.ID] = *session
+	return nil
+}
+
+// RotateSession swaps the current token of the family, revoking it when an old token is replayed
+func (r *MemorySessionRepository) RotateSession(ctx context.Context, id, oldJTI, newJTI uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	session, ok := r.sessions[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if session.RevokedAt != nil || !session.ExpiresAt.After(time.Now()) {
+		return ErrSessionRevoked
+	}
+	if session.CurrentJTI != oldJTI {
+		now := time.Now().UTC()
+		session.RevokedAt = &now
+		r.sessions[id] = session
+		return ErrTokenReused
+	}
+
+	session.CurrentJTI = newJTI
+	r.sessions[id] = session
+	return nil
+}
+
+// RevokeSession marks the family revoked
+func (r *MemorySessionRepository) RevokeSession(ctx context.Context, id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.revokeLocked(func(s Session) bool { return s.ID == id })
+	return nil
+}
+
+// RevokeUserSessions marks every family of the user revoked
+func (r *MemorySessionRepository) RevokeUserSessions(ctx context.Context, userID uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.revokeLocked(func(s Session) bool { return s.UserID == userID })
+	return nil
+}
+
+// RevokeToken adds the token to the revocation list and drops the entries that expired
+func (r *MemorySessionRepository) RevokeToken(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for id, expires := range r.revoked {
+		if !expires.After(now) {
+			delete(r.revoked, id)
+		}
+	}
+	if expiresAt.After(now) {
+		r.revoked[tokenID] = expiresAt
+	}
+	return nil
+}
+
+// IsTokenRevoked reports whether the token is on the revocation list and not expired yet
+func (r *MemorySessionRepository) IsTokenRevoked(ctx context.Context, tokenID string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	expires, ok := r.revoked[tokenID]
+	return ok && expires.After(time.Now()), nil
+}
+
+// revokeLocked revokes the families matching the filter that are not revoked yet, r.mu must be held
+func (r *MemorySessionRepository) revokeLocked(match func(Session) bool) {
+	now := time.Now().UTC()
+	for id, session := range r.sessions {
+		if session.RevokedAt == nil && match(session) {
+			session.RevokedAt = &now
+			r.sessions[id] = session
+		}
+	}
+}
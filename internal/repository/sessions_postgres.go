@@ -0,0 +1,145 @@
+// sessions_postgres.go is the SessionRepository implementation backed by the pgx connection pool
+// the tables are created in 008_auth_sessions.sql
+
+package repository
+
+import (
+	"context"
+	"errors"
+	"feast-friends-api/internal/utils"
+	"feast-friends-api/pkg/logger"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// PostgresSessionRepository reads and writes refresh token families and revoked tokens
+type PostgresSessionRepository struct {
+	db *pgxpool.Pool
+}
+
+// make sure the implementation satisfies the interface at compile time
+var _ SessionRepository = (*PostgresSessionRepository)(nil)
+
+// NewPostgresSessionRepository creates the repository using the given pool (usually utils.DB)
+func NewPostgresSessionRepository(db *pgxpool.Pool) *PostgresSessionRepository {
+	return &PostgresSessionRepository{db: db}
+}
+
+// CreateSession inserts the family
+func (r *PostgresSessionRepository) CreateSession(ctx context.Context, session *Session) error {
+	err := utils.ExecuteQueryRowContext(ctx, r.db,
+		`INSERT INTO public.auth_sessions (id, user_id, current_jti, expires_at)
+		 VALUES ($1, $2, $3, $4)
+		 RETURNING created_at`,
+		session.ID, session.UserID, session.CurrentJTI, session.ExpiresAt,
+	).Scan(&session.CreatedAt)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to create session for user %v: %v", session.UserID, err)
+		return err
+	}
+	session.RevokedAt = nil
+	return nil
+}
+
+// RotateSession locks the family row so two refreshes with the same token cannot both succeed
+func (r *PostgresSessionRepository) RotateSession(ctx context.Context, id, oldJTI, newJTI uuid.UUID) error {
+	// WithTx rolls back when the callback fails, so reuse is reported after the revocation is committed
+	reused := false
+	err := utils.WithTx(ctx, r.db, func(tx pgx.Tx) error {
+		reused = false // WithTx may run this again after a deadlock
+		var current uuid.UUID
+		var active bool
+		err := utils.ExecuteQueryRowContext(ctx, tx,
+			`SELECT current_jti, revoked_at IS NULL AND expires_at > now()
+			 FROM public.auth_sessions WHERE id = $1 FOR UPDATE`,
+			id,
+		).Scan(&current, &active)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if !active {
+			return ErrSessionRevoked
+		}
+
+		if current != oldJTI {
+			reused = true
+			_, err = utils.ExecuteNonQueryContext(ctx, tx, `UPDATE public.auth_sessions SET revoked_at = now() WHERE id = $1`, id)
+			return err
+		}
+
+		_, err = utils.ExecuteNonQueryContext(ctx, tx, `UPDATE public.auth_sessions SET current_jti = $2 WHERE id = $1`, id, newJTI)
+		return err
+	})
+
+	switch {
+	case err == nil && reused:
+		logger.WarnContext(ctx, "refresh token reuse detected on session %v, session revoked", id)
+		return ErrTokenReused
+	case err == nil, errors.Is(err, ErrNotFound), errors.Is(err, ErrSessionRevoked):
+		return err
+	default:
+		logger.ErrorContext(ctx, "failed to rotate session %v: %v", id, err)
+		return err
+	}
+}
+
+// RevokeSession sets revoked_at on the family
+func (r *PostgresSessionRepository) RevokeSession(ctx context.Context, id uuid.UUID) error {
+	_, err := utils.ExecuteNonQueryContext(ctx, r.db,
+		`UPDATE public.auth_sessions SET revoked_at = now() WHERE id = $1 AND revoked_at IS NULL`, id,
+	)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to revoke session %v: %v", id, err)
+	}
+	return err
+}
+
+// RevokeUserSessions sets revoked_at on every active family of the user
+func (r *PostgresSessionRepository) RevokeUserSessions(ctx context.Context, userID uuid.UUID) error {
+	_, err := utils.ExecuteNonQueryContext(ctx, r.db,
+		`UPDATE public.auth_sessions SET revoked_at = now() WHERE user_id = $1 AND revoked_at IS NULL`, userID,
+	)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to revoke sessions of user %v: %v", userID, err)
+	}
+	return err
+}
+
+// RevokeToken inserts the token id and deletes the entries that expired, the list only grows with live tokens
+func (r *PostgresSessionRepository) RevokeToken(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	err := utils.WithTx(ctx, r.db, func(tx pgx.Tx) error {
+		_, err := utils.ExecuteNonQueryContext(ctx, tx,
+			`INSERT INTO public.revoked_tokens (token_id, expires_at) VALUES ($1, $2)
+			 ON CONFLICT (token_id) DO NOTHING`,
+			tokenID, expiresAt,
+		)
+		if err != nil {
+			return err
+		}
+		_, err = utils.ExecuteNonQueryContext(ctx, tx, `DELETE FROM public.revoked_tokens WHERE expires_at <= now()`)
+		return err
+	})
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to revoke token: %v", err)
+	}
+	return err
+}
+
+// IsTokenRevoked looks the token id up in the revocation list
+func (r *PostgresSessionRepository) IsTokenRevoked(ctx context.Context, tokenID string) (bool, error) {
+	var revoked bool
+	err := utils.ExecuteQueryRowContext(ctx, r.db,
+		`SELECT EXISTS (SELECT 1 FROM public.revoked_tokens WHERE token_id = $1 AND expires_at > now())`, tokenID,
+	).Scan(&revoked)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to check token revocation: %v", err)
+		return false, err
+	}
+	return revoked, nil
+}
@@ -0,0 +1,31 @@
+// social.go defines the SocialRepository interface for likes and follows
+// every write is idempotent: liking twice or unfollowing someone you dont follow is not an error
+// the likes_count / followers_count / following_count counters are kept in sync by the implementations
+
+package repository
+
+import (
+	"context"
+	"feast-friends-api/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// SocialRepository describes the operations on public.likes and public.follows
+type SocialRepository interface {
+	// Like records that the user likes the post, returns ErrNotFound if the post does not exist
+	Like(ctx context.Context, userID, postID uuid.UUID) error
+	// Unlike removes the like if there is one
+	Unlike(ctx context.Context, userID, postID uuid.UUID) error
+	// LikedPosts returns which of the posts the user likes, posts without a like are left out
+	LikedPosts(ctx context.Context, userID uuid.UUID, postIDs []uuid.UUID) (map[uuid.UUID]bool, error)
+	// Follow records that follower follows following
+	// returns ErrNotFound if either user does not exist and ErrInvalidRelation for self follows
+	Follow(ctx context.Context, followerID, followingID uuid.UUID) error
+	// Unfollow removes the follow if there is one
+	Unfollow(ctx context.Context, followerID, followingID uuid.UUID) error
+	// ListFollowers returns a page of users following userID, most recent first, plus the total count
+	ListFollowers(ctx context.Context, userID uuid.UUID, limit, offset int) ([]models.User, int, error)
+	// ListFollowing returns a page of users userID follows, most recent first, plus the total count
+	ListFollowing(ctx context.Context, userID uuid.UUID, limit, offset int) ([]models.User, int, error)
+}
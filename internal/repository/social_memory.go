@@ -0,0 +1,195 @@
+// social_memory.go is an in-memory SocialRepository used by tests and local experiments
+// it updates the counters on the in-memory posts and users the same way the database triggers do
+
+package repository
+
+import (
+	"context"
+	"feast-friends-api/internal/models"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// edge is a like or follow between two ids and when it was created
+type edge struct {
+	from, to  uuid.UUID
+	createdAt time.Time
+}
+
+// MemorySocialRepository keeps likes, follows and the known users in memory
+type MemorySocialRepository struct {
+	mu      sync.Mutex
+	posts   *MemoryPostRepository
+	users   map[uuid.UUID]models.User
+	likes   map[[2]uuid.UUID]edge
+	follows map[[2]uuid.UUID]edge
+}
+
+// make sure the implementation satisfies the interface at compile time
+var _ SocialRepository = (*MemorySocialRepository)(nil)
+
+// NewMemorySocialRepository creates an empty repository that keeps like counts on the given posts
+func NewMemorySocialRepository(posts *MemoryPostRepository) *MemorySocialRepository {
+	return &MemorySocialRepository{
+		posts:   posts,
+		users:   make(map[uuid.UUID]models.User),
+		likes:   make(map[[2]uuid.UUID]edge),
+		follows: make(map[[2]uuid.UUID]edge),
+	}
+}
+
+// AddUser registers a user that can follow or be followed
+func (r *MemorySocialRepository) AddUser(user models.User) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.users[user.ID] = user
+}
+
+// Like stores the like and bumps the post likes_count once
+func (r *MemorySocialRepository) Like(ctx context.Context, userID, postID uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := [2]uuid.UUID{userID, postID}
+	if _, ok := r.likes[key]; ok {
+		return nil
+	}
+	if !r.adjustLikes(postID, 1) {
+		return ErrNotFound
+	}
+	r.likes[key] = edge{from: userID, to: postID, createdAt: time.Now().UTC()}
+	return nil
+}
+
+// Unlike removes the like and decrements the post likes_count if it existed
+func (r *MemorySocialRepository) Unlike(ctx context.Context, userID, postID uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := [2]uuid.UUID{userID, postID}
+	if _, ok := r.likes[key]; !ok {
+		return nil
+	}
+	delete(r.likes, key)
+	r.adjustLikes(postID, -1)
+	return nil
+}
+
+// LikedPosts checks every post against the stored likes
+func (r *MemorySocialRepository) LikedPosts(ctx context.Context, userID uuid.UUID, postIDs []uuid.UUID) (map[uuid.UUID]bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	liked := make(map[uuid.UUID]bool)
+	for _, postID := range postIDs {
+		if _, ok := r.likes[[2]uuid.UUID{userID, postID}]; ok {
+			liked[postID] = true
+		}
+	}
+	return liked, nil
+}
+
+// Follow stores the follow and bumps both users counters once
+func (r *MemorySocialRepository) Follow(ctx context.Context, followerID, followingID uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if followerID == followingID {
+		return ErrInvalidRelation
+	}
+	_, followerOK := r.users[followerID]
+	_, followingOK := r.users[followingID]
+	if !followerOK || !followingOK {
+		return ErrNotFound
+	}
+
+	key := [2]uuid.UUID{followerID, followingID}
+	if _, ok := r.follows[key]; ok {
+		return nil
+	}
+	r.follows[key] = edge{from: followerID, to: followingID, createdAt: time.Now().UTC()}
+	r.adjustFollows(followerID, followingID, 1)
+	return nil
+}
+
+// Unfollow removes the follow and decrements both users counters if it existed
+func (r *MemorySocialRepository) Unfollow(ctx context.Context, followerID, followingID uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := [2]uuid.UUID{followerID, followingID}
+	if _, ok := r.follows[key]; !ok {
+		return nil
+	}
+	delete(r.follows, key)
+	r.adjustFollows(followerID, followingID, -1)
+	return nil
+}
+
+// ListFollowers returns the users following userID, most recent first
+func (r *MemorySocialRepository) ListFollowers(ctx context.Context, userID uuid.UUID, limit, offset int) ([]models.User, int, error) {
+	return r.listFollows(func(e edge) (bool, uuid.UUID) { return e.to == userID, e.from }, limit, offset)
+}
+
+// ListFollowing returns the users userID follows, most recent first
+func (r *MemorySocialRepository) ListFollowing(ctx context.Context, userID uuid.UUID, limit, offset int) ([]models.User, int, error) {
+	return r.listFollows(func(e edge) (bool, uuid.UUID) { return e.from == userID, e.to }, limit, offset)
+}
+
+// listFollows keeps the follows match accepts and returns a page of the users it points to
+func (r *MemorySocialRepository) listFollows(match func(edge) (bool, uuid.UUID), limit, offset int) ([]models.User, int, error) {
+	limit, offset = clampPage(limit, offset)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	matched := []edge{}
+	for _, e := range r.follows {
+		if ok, _ := match(e); ok {
+			matched = append(matched, e)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i].createdAt.After(matched[j].createdAt) })
+
+	total := len(matched)
+	users := []models.User{}
+	for i := offset; i < total && i < offset+limit; i++ {
+		_, id := match(matched[i])
+		users = append(users, r.users[id])
+	}
+	return users, total, nil
+}
+
+// adjustLikes changes the likes_count of the post, returns false if the post does not exist
+func (r *MemorySocialRepository) adjustLikes(postID uuid.UUID, delta int) bool {
+	r.posts.mu.Lock()
+	defer r.posts.mu.Unlock()
+
+	post, ok := r.posts.posts[postID]
+	if !ok {
+		return false
+	}
+	post.LikesCount += delta
+	r.posts.posts[postID] = post
+	return true
+}
+
+// adjustFollows changes the following/followers counters of both users
+func (r *MemorySocialRepository) adjustFollows(followerID, followingID uuid.UUID, delta int) {
+	follower := r.users[followerID]
+	follower.FollowingCount += delta
+	r.users[followerID] = follower
+
+	following := r.users[followingID]
+	following.FollowersCount += delta
+	r.users[followingID] = following
+}
+
+// user returns a copy of the registered user, or an empty user if it is unknown
+func (r *MemorySocialRepository) user(id uuid.UUID) models.User {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.users[id]
+}
@@ -0,0 +1,138 @@
+// social_postgres.go is the SocialRepository implementation backed by the pgx connection pool
+// counters are updated by the triggers in 002_social_counters.sql in the same transaction as the write
+
+package repository
+
+import (
+	"context"
+	"feast-friends-api/internal/models"
+	"feast-friends-api/internal/utils"
+	"feast-friends-api/pkg/logger"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// PostgresSocialRepository reads and writes likes and follows
+type PostgresSocialRepository struct {
+	db *pgxpool.Pool
+}
+
+// make sure the implementation satisfies the interface at compile time
+var _ SocialRepository = (*PostgresSocialRepository)(nil)
+
+// NewPostgresSocialRepository creates the repository using the given pool (usually utils.DB)
+func NewPostgresSocialRepository(db *pgxpool.Pool) *PostgresSocialRepository {
+	return &PostgresSocialRepository{db: db}
+}
+
+// Like inserts the like, a second like from the same user is ignored
+func (r *PostgresSocialRepository) Like(ctx context.Context, userID, postID uuid.UUID) error {
+	_, err := utils.ExecuteNonQueryContext(ctx, r.db,
+		`INSERT INTO public.likes (user_id, post_id) VALUES ($1, $2)
+		 ON CONFLICT (user_id, post_id) DO NOTHING`,
+		userID, postID,
+	)
+	return socialWriteError("like", err)
+}
+
+// Unlike deletes the like if it exists
+func (r *PostgresSocialRepository) Unlike(ctx context.Context, userID, postID uuid.UUID) error {
+	_, err := utils.ExecuteNonQueryContext(ctx, r.db, `DELETE FROM public.likes WHERE user_id = $1 AND post_id = $2`, userID, postID)
+	return socialWriteError("unlike", err)
+}
+
+// LikedPosts looks all the posts up in one query
+func (r *PostgresSocialRepository) LikedPosts(ctx context.Context, userID uuid.UUID, postIDs []uuid.UUID) (map[uuid.UUID]bool, error) {
+	liked := make(map[uuid.UUID]bool)
+	if len(postIDs) == 0 {
+		return liked, nil
+	}
+
+	rows, err := utils.ExecuteQueryContext(ctx, r.db,
+		`SELECT post_id FROM public.likes WHERE user_id = $1 AND post_id = ANY($2)`, userID, postIDs,
+	)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to load likes of user %v: %v", userID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var postID uuid.UUID
+		if err := rows.Scan(&postID); err != nil {
+			return nil, err
+		}
+		liked[postID] = true
+	}
+	return liked, rows.Err()
+}
+
+// Follow inserts the follow, following the same user twice is ignored
+func (r *PostgresSocialRepository) Follow(ctx context.Context, followerID, followingID uuid.UUID) error {
+	_, err := utils.ExecuteNonQueryContext(ctx, r.db,
+		`INSERT INTO public.follows (follower_id, following_id) VALUES ($1, $2)
+		 ON CONFLICT (follower_id, following_id) DO NOTHING`,
+		followerID, followingID,
+	)
+	return socialWriteError("follow", err)
+}
+
+// Unfollow deletes the follow if it exists
+func (r *PostgresSocialRepository) Unfollow(ctx context.Context, followerID, followingID uuid.UUID) error {
+	_, err := utils.ExecuteNonQueryContext(ctx, r.db, `DELETE FROM public.follows WHERE follower_id = $1 AND following_id = $2`, followerID, followingID)
+	return socialWriteError("unfollow", err)
+}
+
+// ListFollowers returns the users following userID
+func (r *PostgresSocialRepository) ListFollowers(ctx context.Context, userID uuid.UUID, limit, offset int) ([]models.User, int, error) {
+	return r.listFollows(ctx, "following_id", "follower_id", userID, limit, offset)
+}
+
+// ListFollowing returns the users userID follows
+func (r *PostgresSocialRepository) ListFollowing(ctx context.Context, userID uuid.UUID, limit, offset int) ([]models.User, int, error) {
+	return r.listFollows(ctx, "follower_id", "following_id", userID, limit, offset)
+}
+
+// listFollows pages through public.follows filtering on matchColumn and loading the user in userColumn
+// the column names are constants chosen by the callers above, never user input
+func (r *PostgresSocialRepository) listFollows(ctx context.Context, matchColumn, userColumn string, userID uuid.UUID, limit, offset int) ([]models.User, int, error) {
+	limit, offset = clampPage(limit, offset)
+	var total int
+	if err := utils.ExecuteQueryRowContext(ctx, r.db, `SELECT count(*) FROM public.follows WHERE `+matchColumn+` = $1`, userID).Scan(&total); err != nil {
+		logger.ErrorContext(ctx, "failed to count follows for user %v: %v", userID, err)
+		return nil, 0, err
+	}
+
+	rows, err := utils.ExecuteQueryContext(ctx, r.db,
+		`SELECT `+userColumns+`
+		 FROM public.follows f
+		 JOIN `+userFrom+` ON p.id = f.`+userColumn+`
+		 WHERE f.`+matchColumn+` = $1
+		 ORDER BY f.created_at DESC, p.id
+		 LIMIT $2 OFFSET $3`,
+		userID, limit, offset,
+	)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to list follows for user %v: %v", userID, err)
+		return nil, 0, err
+	}
+
+	users, err := collectUsers(rows)
+	return users, total, err
+}
+
+// socialWriteError maps constraint violations to repository errors and logs anything unexpected
+func socialWriteError(action string, err error) error {
+	if err == nil {
+		return nil
+	}
+	switch pgErrorCode(err) {
+	case pgForeignKeyViolation:
+		return ErrNotFound
+	case pgCheckViolation:
+		return ErrInvalidRelation
+	}
+	logger.Error("failed to %s: %v", action, err)
+	return err
+}
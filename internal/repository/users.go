@@ -0,0 +1,51 @@
+// users.go contains the shared query pieces used to load models.User rows
+// a user is a public.profiles row joined with auth.users for the email and sign up date
+
+package repository
+
+import (
+	"feast-friends-api/internal/models"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// columns selected for every user query, kept in the same order as scanUser
+const userColumns = `p.id, COALESCE(u.email, ''), p.username, COALESCE(p.full_name, ''), COALESCE(p.bio, ''),
+	COALESCE(p.profile_picture_url, ''), p.followers_count, p.following_count, p.posts_count,
+	COALESCE(u.created_at, p.updated_at)`
+
+// userFrom is the FROM clause matching userColumns
+const userFrom = `public.profiles p LEFT JOIN auth.users u ON u.id = p.id`
+
+// scanUser reads one row selected with userColumns into a user
+func scanUser(row pgx.Row) (*models.User, error) {
+	var user models.User
+	if err := row.Scan(userDest(&user)...); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// userDest returns the scan destinations matching userColumns
+func userDest(user *models.User) []interface{} {
+	return []interface{}{
+		&user.ID, &user.Email, &user.Username, &user.FullName, &user.Bio,
+		&user.AvatarURL, &user.FollowersCount, &user.FollowingCount, &user.PostsCount,
+		&user.CreatedAt,
+	}
+}
+
+// collectUsers scans every row and closes rows when done
+func collectUsers(rows pgx.Rows) ([]models.User, error) {
+	defer rows.Close()
+
+	users := []models.User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, *user)
+	}
+	return users, rows.Err()
+}
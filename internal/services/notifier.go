@@ -0,0 +1,48 @@
+// Package services contains business logic shared by handlers that does not belong to a single repository.
+package services
+
+import (
+	"context"
+	"feast-friends-api/internal/models"
+	"feast-friends-api/internal/realtime"
+	"feast-friends-api/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+// Notifier tells users about things that happened to them while they were not looking
+// it is an interface so push notifications, emails or websocket events can be plugged in later
+type Notifier interface {
+	// WaitlistPromoted is sent when a user moved from the waitlist to going
+	WaitlistPromoted(ctx context.Context, event models.Event, userID uuid.UUID) error
+}
+
+// LogNotifier only writes notifications to the app log, handy when no realtime hub is running
+type LogNotifier struct{}
+
+// WaitlistPromoted logs the promotion
+func (LogNotifier) WaitlistPromoted(ctx context.Context, event models.Event, userID uuid.UUID) error {
+	logger.Info("user %v promoted from the waitlist of event %v (%s)", userID, event.ID, event.Title)
+	return nil
+}
+
+// RealtimeNotifier pushes notifications to the realtime stream of the user (GET /api/v1/realtime)
+type RealtimeNotifier struct {
+	hub realtime.Hub
+}
+
+// NewRealtimeNotifier creates a notifier publishing on hub
+func NewRealtimeNotifier(hub realtime.Hub) *RealtimeNotifier {
+	return &RealtimeNotifier{hub: hub}
+}
+
+// WaitlistPromoted sends an event.waitlist_promoted event with the event to the promoted user
+// users that are not connected do not get it, their rsvp already says going
+func (n *RealtimeNotifier) WaitlistPromoted(ctx context.Context, event models.Event, userID uuid.UUID) error {
+	logger.InfoContext(ctx, "user %v promoted from the waitlist of event %v (%s)", userID, event.ID, event.Title)
+	return n.hub.Publish(ctx, []uuid.UUID{userID}, realtime.Event{
+		Type:   realtime.EventWaitlistPromoted,
+		UserID: userID,
+		Event:  &event,
+	})
+}
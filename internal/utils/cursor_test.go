@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	id := uuid.MustParse("6f1c2a8e-3b4d-4e5f-8a9b-0c1d2e3f4a5b")
+	tests := []struct {
+		name      string
+		createdAt time.Time
+	}{
+		{"utc", time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)},
+		{"nanoseconds", time.Date(2024, 3, 1, 12, 30, 0, 123456789, time.UTC)},
+		{"other zone", time.Date(2024, 3, 1, 14, 30, 0, 0, time.FixedZone("CEST", 2*60*60))},
+		{"zero", time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeCursor(EncodeCursor(Cursor{CreatedAt: tt.createdAt, ID: id}))
+			if err != nil {
+				t.Fatalf("DecodeCursor: %v", err)
+			}
+			if !got.CreatedAt.Equal(tt.createdAt) {
+				t.Errorf("CreatedAt = %v, want %v", got.CreatedAt, tt.createdAt)
+			}
+			if got.ID != id {
+				t.Errorf("ID = %v, want %v", got.ID, id)
+			}
+		})
+	}
+}
+
+func TestDecodeCursorInvalid(t *testing.T) {
+	encode := func(raw string) string { return base64.RawURLEncoding.EncodeToString([]byte(raw)) }
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{"empty", ""},
+		{"not base64", "not a cursor!"},
+		{"padding", encode("2024-03-01T12:30:00Z|6f1c2a8e-3b4d-4e5f-8a9b-0c1d2e3f4a5b") + "=="},
+		{"no separator", encode("2024-03-01T12:30:00Z")},
+		{"bad time", encode("yesterday|6f1c2a8e-3b4d-4e5f-8a9b-0c1d2e3f4a5b")},
+		{"bad id", encode("2024-03-01T12:30:00Z|42")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecodeCursor(tt.cursor); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("DecodeCursor(%q) error = %v, want ErrInvalidCursor", tt.cursor, err)
+			}
+		})
+	}
+}
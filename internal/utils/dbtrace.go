@@ -0,0 +1,68 @@
+// dbtrace.go adds OpenTelemetry spans around the queries run through the Execute* helpers and the WithTx transactions
+// spans are named after the statement ("SELECT posts", "INSERT likes") so traces stay readable
+// and the full sql is kept in the db.query.text attribute, the arguments are never recorded
+// the repositories run all their sql through them, so every query shows up under the request span
+
+package utils
+
+import (
+	"context"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// dbTracer starts the query spans, it does nothing until tracing.Setup installs a provider
+var dbTracer = otel.Tracer("feast-friends-api/internal/utils")
+
+// startQuerySpan starts a client span for the query as a child of the span in ctx
+func startQuerySpan(ctx context.Context, query string) (context.Context, trace.Span) {
+	operation, table := statementName(query)
+	name := operation
+	if table != "" {
+		name += " " + table
+	}
+	return dbTracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBOperationName(operation),
+			semconv.DBCollectionName(table),
+			semconv.DBQueryText(query),
+		),
+	)
+}
+
+// endQuerySpan marks the span as failed when err is set and ends it
+func endQuerySpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// statementName returns the sql command and the table it works on, e.g ("SELECT", "posts").
+// It only looks at the first keyword and the word after FROM/INTO/UPDATE, table is "" when not found.
+func statementName(query string) (operation, table string) {
+	words := strings.Fields(query)
+	if len(words) == 0 {
+		return "QUERY", ""
+	}
+	operation = strings.ToUpper(words[0])
+
+	after := map[string]string{"SELECT": "FROM", "DELETE": "FROM", "INSERT": "INTO", "UPDATE": "UPDATE"}[operation]
+	if after == "" {
+		return operation, ""
+	}
+	for i, word := range words[:len(words)-1] {
+		if strings.EqualFold(word, after) {
+			table = strings.Trim(words[i+1], `"();,`)
+			break
+		}
+	}
+	return operation, table
+}
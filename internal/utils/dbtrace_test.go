@@ -0,0 +1,191 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// fakeQuerier answers every query with err, rows have no data
+type fakeQuerier struct {
+	err error
+}
+
+func (q fakeQuerier) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	return nil, q.err
+}
+
+func (q fakeQuerier) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	if q.err != nil {
+		return nil, q.err
+	}
+	return &fakeRows{}, nil
+}
+
+func (q fakeQuerier) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	return fakeRow{err: q.err}
+}
+
+type fakeRow struct {
+	err error
+}
+
+func (r fakeRow) Scan(dest ...interface{}) error { return r.err }
+
+type fakeRows struct {
+	pgx.Rows
+}
+
+func (r *fakeRows) Next() bool { return false }
+func (r *fakeRows) Close()     {}
+func (r *fakeRows) Err() error { return nil }
+
+var (
+	spanRecorder    = tracetest.NewSpanRecorder()
+	installRecorder sync.Once
+)
+
+// recordSpans returns the spans ended since it was called
+// dbTracer only picks up the first provider set globally, so all the tests share one recorder
+func recordSpans(t *testing.T) func() []sdktrace.ReadOnlySpan {
+	t.Helper()
+	installRecorder.Do(func() {
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder)))
+	})
+	seen := len(spanRecorder.Ended())
+	return func() []sdktrace.ReadOnlySpan {
+		return spanRecorder.Ended()[seen:]
+	}
+}
+
+func TestStatementName(t *testing.T) {
+	tests := []struct {
+		query     string
+		operation string
+		table     string
+	}{
+		{"SELECT id FROM public.posts WHERE id = $1", "SELECT", "public.posts"},
+		{"select count(*) from likes", "SELECT", "likes"},
+		{"INSERT INTO public.comments (id) VALUES ($1)", "INSERT", "public.comments"},
+		{`UPDATE "events" SET title = $2`, "UPDATE", "events"},
+		{"DELETE FROM follows WHERE follower_id = $1", "DELETE", "follows"},
+		{"SELECT EXISTS(SELECT 1 FROM posts), (SELECT count(*) FROM comments)", "SELECT", "posts"},
+		{"SELECT now()", "SELECT", ""},
+		{"SELECT pg_notify($1, $2)", "SELECT", ""},
+		{"LISTEN realtime_events", "LISTEN", ""},
+		{"   ", "QUERY", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			operation, table := statementName(tt.query)
+			if operation != tt.operation || table != tt.table {
+				t.Errorf("statementName(%q) = (%q, %q), want (%q, %q)", tt.query, operation, table, tt.operation, tt.table)
+			}
+		})
+	}
+}
+
+func TestQueryHelpersRecordSpans(t *testing.T) {
+	failure := errors.New("connection refused")
+
+	tests := []struct {
+		name   string
+		run    func(db Querier)
+		status codes.Code
+	}{
+		{
+			name: "query row",
+			run: func(db Querier) {
+				var id int
+				ExecuteQueryRowContext(context.Background(), db, "SELECT id FROM public.posts WHERE id = $1", 1).Scan(&id)
+			},
+			status: codes.Unset,
+		},
+		{
+			name: "query row without rows",
+			run: func(db Querier) {
+				var id int
+				ExecuteQueryRowContext(context.Background(), fakeQuerier{err: pgx.ErrNoRows}, "SELECT id FROM public.posts WHERE id = $1", 1).Scan(&id)
+			},
+			status: codes.Unset,
+		},
+		{
+			name: "query row failing",
+			run: func(db Querier) {
+				var id int
+				ExecuteQueryRowContext(context.Background(), fakeQuerier{err: failure}, "SELECT id FROM public.posts WHERE id = $1", 1).Scan(&id)
+			},
+			status: codes.Error,
+		},
+		{
+			name: "query read to the end",
+			run: func(db Querier) {
+				rows, err := ExecuteQueryContext(context.Background(), db, "SELECT id FROM public.posts")
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer rows.Close()
+				for rows.Next() {
+				}
+			},
+			status: codes.Unset,
+		},
+		{
+			name: "query failing",
+			run: func(db Querier) {
+				ExecuteQueryContext(context.Background(), fakeQuerier{err: failure}, "SELECT id FROM public.posts")
+			},
+			status: codes.Error,
+		},
+		{
+			name: "exec failing",
+			run: func(db Querier) {
+				ExecuteNonQueryContext(context.Background(), fakeQuerier{err: failure}, "SELECT id FROM public.posts")
+			},
+			status: codes.Error,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ended := recordSpans(t)
+
+			tt.run(fakeQuerier{})
+
+			spans := ended()
+			if len(spans) != 1 {
+				t.Fatalf("got %d ended spans, want 1", len(spans))
+			}
+			if spans[0].Name() != "SELECT public.posts" {
+				t.Errorf("span name = %q, want %q", spans[0].Name(), "SELECT public.posts")
+			}
+			if spans[0].Status().Code != tt.status {
+				t.Errorf("span status = %v, want %v", spans[0].Status().Code, tt.status)
+			}
+		})
+	}
+}
+
+func TestQueryRowsSpanEndsOnClose(t *testing.T) {
+	ended := recordSpans(t)
+
+	rows, err := ExecuteQueryContext(context.Background(), fakeQuerier{}, "SELECT id FROM public.posts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ended()) != 0 {
+		t.Fatalf("span ended before the rows were closed")
+	}
+	rows.Close()
+	rows.Close()
+	if n := len(ended()); n != 1 {
+		t.Fatalf("got %d ended spans after Close, want 1", n)
+	}
+}
@@ -0,0 +1,220 @@
+// jwks.go caches the JSON Web Key Set used to verify asymmetric (RS256/ES256) tokens
+// keys are fetched on first use and refreshed every JWT_JWKS_REFRESH, a token signed with a kid
+// that is not cached yet (key rotation) triggers an early refetch, at most once per jwksMinRefetch
+// the download runs outside the lock: stale keys keep being served while a refresh runs in the
+// background and concurrent callers waiting for an unknown kid share one request
+
+package utils
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"feast-friends-api/pkg/logger"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// jwksMinRefetch stops tokens with made up kids from hammering the auth server
+	jwksMinRefetch = 30 * time.Second
+	// jwksMaxBody is the largest key set accepted
+	jwksMaxBody = 1 << 20
+)
+
+// ErrUnknownKey is returned when no cached key matches the kid of a token
+var ErrUnknownKey = errors.New("no signing key found for the token")
+
+// jwksCache keeps the public keys of the key set by kid
+type jwksCache struct {
+	url    string
+	ttl    time.Duration
+	client *http.Client
+
+	// refreshes makes concurrent refreshes share one download
+	refreshes singleflight.Group
+
+	mu          sync.RWMutex
+	keys        map[string]crypto.PublicKey
+	fetchedAt   time.Time
+	lastAttempt time.Time
+}
+
+// newJWKSCache creates an empty cache for the key set served at url
+func newJWKSCache(url string, ttl time.Duration) *jwksCache {
+	return &jwksCache{url: url, ttl: ttl, client: &http.Client{Timeout: 5 * time.Second}}
+}
+
+// key returns the public key for kid, refreshing the set when it is stale or the kid is unknown.
+// a stale set is refreshed in the background and its keys keep being served meanwhile, an unknown kid
+// waits for the refresh. when the refresh fails the keys already cached keep being used
+func (c *jwksCache) key(kid string) (crypto.PublicKey, error) {
+	c.mu.RLock()
+	key, ok := c.lookup(kid)
+	stale := time.Since(c.fetchedAt) > c.ttl
+	due := time.Since(c.lastAttempt) >= jwksMinRefetch
+	c.mu.RUnlock()
+
+	switch {
+	case ok && stale && due:
+		go c.refresh()
+	case !ok:
+		// joins a download already running, refresh itself skips it within jwksMinRefetch
+		c.refresh()
+		c.mu.RLock()
+		key, ok = c.lookup(kid)
+		c.mu.RUnlock()
+	}
+
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	return key, nil
+}
+
+// refresh downloads the key set, callers arriving while a download runs wait for that one
+func (c *jwksCache) refresh() {
+	c.refreshes.Do("jwks", func() (interface{}, error) {
+		c.mu.Lock()
+		if time.Since(c.lastAttempt) < jwksMinRefetch {
+			c.mu.Unlock()
+			return nil, nil
+		}
+		c.lastAttempt = time.Now()
+		c.mu.Unlock()
+
+		keys, err := c.fetch()
+		if err != nil {
+			logger.Warn("failed to refresh jwks from %s: %v", c.url, err)
+			return nil, nil
+		}
+
+		c.mu.Lock()
+		c.keys, c.fetchedAt = keys, time.Now()
+		c.mu.Unlock()
+		return nil, nil
+	})
+}
+
+// lookup finds kid in the cached keys, a token without kid matches a set with a single key
+// the caller holds mu
+func (c *jwksCache) lookup(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(c.keys) == 1 {
+		for _, key := range c.keys {
+			return key, true
+		}
+	}
+	key, ok := c.keys[kid]
+	return key, ok
+}
+
+// fetch downloads and parses the key set, keys that are not for signatures or cannot be parsed are skipped
+func (c *jwksCache) fetch() (map[string]crypto.PublicKey, error) {
+	if c.url == "" {
+		return nil, errors.New("no jwks url configured")
+	}
+
+	res, err := c.client.Get(c.url)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", res.StatusCode)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(io.LimitReader(res.Body, jwksMaxBody)).Decode(&set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			logger.Warn("skipping jwk %q: %v", k.Kid, err)
+			continue
+		}
+		keys[k.Kid] = key
+	}
+	return keys, nil
+}
+
+// jwk is one key of the set, only the RSA and P-256 EC members are read
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// publicKey converts the jwk into an *rsa.PublicKey or *ecdsa.PublicKey
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New("rsa exponent is too large")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+		// ECDH fails for points that are not on the curve
+		if _, err := key.ECDH(); err != nil {
+			return nil, err
+		}
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+// decodeBigInt decodes a base64url (unpadded) big endian integer
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, errors.New("empty integer")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
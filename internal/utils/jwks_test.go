@@ -0,0 +1,117 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// jwksServer serves one RSA key under kid and counts the requests, delay is read on every request.
+// the private key is returned to sign test tokens with
+func jwksServer(t *testing.T, kid string, delay *atomic.Int64) (*httptest.Server, *atomic.Int32, *rsa.PrivateKey) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := json.Marshal(map[string]interface{}{"keys": []map[string]string{{
+		"kty": "RSA",
+		"kid": kid,
+		"use": "sig",
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}}})
+
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		time.Sleep(time.Duration(delay.Load()))
+		w.Write(body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &hits, key
+}
+
+func TestJWKSCacheServesStaleKeysWhileRefreshing(t *testing.T) {
+	var delay atomic.Int64
+	srv, hits, _ := jwksServer(t, "k1", &delay)
+	cache := newJWKSCache(srv.URL, time.Minute)
+
+	if _, err := cache.key("k1"); err != nil {
+		t.Fatalf("first lookup: %v", err)
+	}
+
+	// make the set stale and the endpoint slow, the cached key must still come back right away
+	cache.mu.Lock()
+	cache.fetchedAt = time.Now().Add(-time.Hour)
+	cache.lastAttempt = time.Now().Add(-time.Hour)
+	cache.mu.Unlock()
+	delay.Store(int64(300 * time.Millisecond))
+
+	start := time.Now()
+	if _, err := cache.key("k1"); err != nil {
+		t.Fatalf("stale lookup: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Fatalf("stale lookup waited %s for the refresh", elapsed)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for hits.Load() < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := hits.Load(); got != 2 {
+		t.Fatalf("jwks fetched %d times, want 2", got)
+	}
+}
+
+func TestJWKSCacheUnknownKid(t *testing.T) {
+	tests := []struct {
+		name        string
+		lastAttempt time.Duration // how long ago the previous fetch was tried
+		wantHits    int32
+	}{
+		{name: "refetches once for concurrent lookups", lastAttempt: time.Hour, wantHits: 1},
+		{name: "does not refetch within the min interval", lastAttempt: time.Second, wantHits: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var delay atomic.Int64
+			delay.Store(int64(50 * time.Millisecond))
+			srv, hits, _ := jwksServer(t, "rotated", &delay)
+			cache := newJWKSCache(srv.URL, time.Minute)
+			cache.keys = nil
+			cache.fetchedAt = time.Now()
+			cache.lastAttempt = time.Now().Add(-tt.lastAttempt)
+
+			var wg sync.WaitGroup
+			for i := 0; i < 10; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					_, err := cache.key("rotated")
+					if tt.wantHits > 0 && err != nil {
+						t.Errorf("lookup: %v", err)
+					}
+					if tt.wantHits == 0 && !errors.Is(err, ErrUnknownKey) {
+						t.Errorf("lookup error = %v, want ErrUnknownKey", err)
+					}
+				}()
+			}
+			wg.Wait()
+
+			if got := hits.Load(); got != tt.wantHits {
+				t.Fatalf("jwks fetched %d times, want %d", got, tt.wantHits)
+			}
+		})
+	}
+}
@@ -1,8 +1,9 @@
 // jwt.go contains all the functions to handle JWT tokens
 // it includes token generation, validation and extraction of claims
 // it uses golang-jwt/jwt package to handle JWT tokens
-// it also uses gotrue-go package to validate tokens against Supabase Auth
-// JWT secret and expiration are loaded from config package
+// tokens are verified locally (HS256 with the JWT secret, RS256/ES256 with the supabase JWKS),
+// in remote mode they are checked against Supabase Auth with the gotrue-go package instead
+// JWT settings are loaded from config package
 
 package utils
 
@@ -11,38 +12,126 @@ import (
 	"feast-friends-api/internal/config"
 	"feast-friends-api/pkg/logger"
 	"strings"
+	"sync"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"github.com/supabase-community/gotrue-go"
 )
 
+// modes accepted in JWT_MODE
+const (
+	JWTModeLocal  = "local"
+	JWTModeRemote = "remote"
+)
+
 // set variables for JWT and Supabase
 var JWSecret, JWExpiration, url, key = config.Get().JWT.Secret, config.Get().JWT.Expiration, config.Get().Supabase.URL, config.Get().Supabase.AKey
 
-// this function will verify if the JWT secret is set in the config
+// jwks is the key set cache of JWT_JWKS_URL, only used for asymmetric tokens. it is created on first use
+// and replaced when the url or JWT_JWKS_REFRESH change, the JWT settings are read from config.Get() on every token
+var (
+	jwksMu sync.Mutex
+	jwks   *jwksCache
+)
+
+// currentJWKS returns the cache of the configured key set
+func currentJWKS() *jwksCache {
+	settings := config.Get().JWT
+	jwksMu.Lock()
+	defer jwksMu.Unlock()
+	if jwks == nil || jwks.url != settings.JWKSURL || jwks.ttl != settings.JWKSRefresh {
+		jwks = newJWKSCache(settings.JWKSURL, settings.JWKSRefresh)
+	}
+	return jwks
+}
+
+// NewAuthClient creates a gotrue client for the configured auth url, apiKey is the anon or the service key
+// gotrue.New expects a project reference so the url from the config is set as a custom url
+func NewAuthClient(apiKey string) gotrue.Client {
+	return gotrue.New("", apiKey).WithCustomGoTrueURL(config.Get().Supabase.AuthURL)
+}
+
+// this function will verify if the JWT config is usable and log how tokens are verified
 func VerifyJWTConfig() {
-	if JWSecret == "" {
+	jwtSettings := config.Get().JWT
+	switch jwtSettings.Mode {
+	case JWTModeRemote:
+		logger.Info("JWT tokens are verified remotely against supabase auth")
+	case JWTModeLocal:
+		logger.Info("JWT tokens are verified locally (issuer %q, audience %q)", jwtSettings.Issuer, jwtSettings.Audience)
+	default:
+		logger.Error("unknown JWT_MODE %q, tokens are verified locally", jwtSettings.Mode)
+	}
+
+	if jwtSettings.Secret == "" {
 		logger.Error("JWT secret is not set in the config. Cannot start application.")
 	} else {
 		logger.Info("JWT secret is set in the config")
 	}
-
+	if jwtSettings.Mode != JWTModeRemote && jwtSettings.JWKSURL == "" {
+		logger.Warn("JWT_JWKS_URL is not set, only HS256 tokens can be verified")
+	}
 }
 
 // this function will validate a JWT token and return the user ID if valid
-// it uses gotrue-go package to validate the token against Supabase Auth
+// local mode checks the signature, exp, aud and iss without a network call (besides refreshing the JWKS)
 func ValidateToken(tokenString string) (userID string, err error) {
 
 	tokenString = strings.TrimPrefix(tokenString, "Bearer ") // trimmng the Bearer prefix if present
 
-	client := gotrue.New(url, key) // create a new gotrue client using supabase url and anon key
-	if client == nil {
-		logger.Error("failed to create gotrue client as it is nil")
-		return "", errors.New("gotrue client is nil")
+	if config.Get().JWT.Mode == JWTModeRemote {
+		return validateRemote(tokenString)
+	}
+	return validateLocal(tokenString)
+}
+
+// validateLocal verifies the token in process and returns its subject, which must be a user uuid
+func validateLocal(tokenString string) (string, error) {
+	jwtSettings := config.Get().JWT
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg(), jwt.SigningMethodRS256.Alg(), jwt.SigningMethodES256.Alg()}),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(jwtSettings.Leeway),
+	}
+	if jwtSettings.Audience != "" {
+		options = append(options, jwt.WithAudience(jwtSettings.Audience))
+	}
+	if jwtSettings.Issuer != "" {
+		options = append(options, jwt.WithIssuer(jwtSettings.Issuer))
+	}
+
+	var claims jwt.RegisteredClaims
+	if _, err := jwt.ParseWithClaims(tokenString, &claims, verificationKey, options...); err != nil {
+		logger.Warn("rejected token: %v", err)
+		return "", err
+	}
+
+	if _, err := uuid.Parse(claims.Subject); err != nil {
+		logger.Warn("rejected token with invalid subject %q", claims.Subject)
+		return "", errors.New("token subject is not a user id")
 	}
+	return claims.Subject, nil
+}
+
+// verificationKey picks the key for the token algorithm, the parser already restricted the algorithms
+func verificationKey(token *jwt.Token) (interface{}, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+		secret := config.Get().JWT.Secret
+		if secret == "" {
+			return nil, errors.New("JWT secret is not set")
+		}
+		return []byte(secret), nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	return currentJWKS().key(kid)
+}
 
+// validateRemote asks supabase auth which user the token belongs to, one network call per token
+func validateRemote(tokenString string) (string, error) {
 	//create user supabase auto checks tor the user the token belongs to
-	user, err := client.WithToken(tokenString).GetUser()
+	user, err := NewAuthClient(config.Get().Supabase.AKey).WithToken(tokenString).GetUser()
 	if err != nil {
 		logger.Error("failed to get user from token: %v", err)
 		return "", err
@@ -0,0 +1,118 @@
+package utils
+
+import (
+	"feast-friends-api/internal/config"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+func TestValidateToken(t *testing.T) {
+	var delay atomic.Int64
+	keys, _, rsaKey := jwksServer(t, "k1", &delay)
+	otherKeys, _, _ := jwksServer(t, "k1", &delay)
+
+	userID := uuid.NewString()
+	gotrue := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/user" || r.Header.Get("Authorization") != "Bearer opaque-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"id": "` + userID + `", "aud": "authenticated", "role": "authenticated"}`))
+	}))
+	t.Cleanup(gotrue.Close)
+
+	local := config.Get().JWT
+	local.Mode = JWTModeLocal
+	local.Secret = "test-secret"
+	local.Audience = "authenticated"
+	local.Issuer = "https://example.supabase.co/auth/v1"
+	local.JWKSURL = keys.URL
+	local.JWKSRefresh = time.Minute
+	local.Leeway = 0
+
+	claims := func(change func(c *jwt.RegisteredClaims)) jwt.RegisteredClaims {
+		c := jwt.RegisteredClaims{
+			Subject:   userID,
+			Audience:  jwt.ClaimStrings{"authenticated"},
+			Issuer:    "https://example.supabase.co/auth/v1",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		}
+		if change != nil {
+			change(&c)
+		}
+		return c
+	}
+	hs256 := func(secret string, c jwt.RegisteredClaims) string {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, c).SignedString([]byte(secret))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	rs256 := func(c jwt.RegisteredClaims) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, c)
+		token.Header["kid"] = "k1"
+		signed, err := token.SignedString(rsaKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+
+	tests := []struct {
+		name     string
+		settings func(s *config.Config)
+		token    string
+		wantErr  bool
+	}{
+		{"hs256", nil, hs256("test-secret", claims(nil)), false},
+		{"bearer prefix", nil, "Bearer " + hs256("test-secret", claims(nil)), false},
+		{"wrong secret", nil, hs256("other-secret", claims(nil)), true},
+		{"secret changed", func(s *config.Config) { s.JWT.Secret = "rotated-secret" }, hs256("test-secret", claims(nil)), true},
+		{"expired", nil, hs256("test-secret", claims(func(c *jwt.RegisteredClaims) {
+			c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+		})), true},
+		{"expired within the leeway", func(s *config.Config) { s.JWT.Leeway = time.Hour }, hs256("test-secret", claims(func(c *jwt.RegisteredClaims) {
+			c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+		})), false},
+		{"no expiry", nil, hs256("test-secret", claims(func(c *jwt.RegisteredClaims) { c.ExpiresAt = nil })), true},
+		{"wrong audience", nil, hs256("test-secret", claims(func(c *jwt.RegisteredClaims) { c.Audience = jwt.ClaimStrings{"anon"} })), true},
+		{"wrong issuer", nil, hs256("test-secret", claims(func(c *jwt.RegisteredClaims) { c.Issuer = "https://evil.example" })), true},
+		{"subject is not a user", nil, hs256("test-secret", claims(func(c *jwt.RegisteredClaims) { c.Subject = "service" })), true},
+		{"rs256 from the jwks", nil, rs256(claims(nil)), false},
+		{"rs256 with another jwks", func(s *config.Config) { s.JWT.JWKSURL = otherKeys.URL }, rs256(claims(nil)), true},
+		{"remote", func(s *config.Config) {
+			s.JWT.Mode = JWTModeRemote
+			s.Supabase.AuthURL = gotrue.URL
+		}, "opaque-token", false},
+		{"remote rejected", func(s *config.Config) {
+			s.JWT.Mode = JWTModeRemote
+			s.Supabase.AuthURL = gotrue.URL
+		}, hs256("test-secret", claims(nil)), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Get()
+			previousJWT, previousSupabase := cfg.JWT, cfg.Supabase
+			t.Cleanup(func() { cfg.JWT, cfg.Supabase = previousJWT, previousSupabase })
+			cfg.JWT = local
+			if tt.settings != nil {
+				tt.settings(cfg)
+			}
+
+			got, err := ValidateToken(tt.token)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateToken() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != userID {
+				t.Errorf("ValidateToken() = %q, want %q", got, userID)
+			}
+		})
+	}
+}
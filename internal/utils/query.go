@@ -0,0 +1,113 @@
+// query.go runs sql through a pool, connection or transaction with the request context
+// every query is cancelled with ctx (e.g. when the client disconnects), gets the default timeout of
+// DB_QUERY_TIMEOUT and a trace span. the repositories run all their sql through these helpers
+
+package utils
+
+import (
+	"context"
+	"errors"
+	"feast-friends-api/internal/config"
+	"sync"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Querier runs sql, *pgxpool.Pool, *pgxpool.Conn and pgx.Tx all implement it
+type Querier interface {
+	Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+// ExecuteQueryContext runs a query on db and returns the resulting rows
+// the timeout and the trace span last until the rows are closed or read to the end
+func ExecuteQueryContext(ctx context.Context, db Querier, query string, args ...interface{}) (pgx.Rows, error) {
+	ctx, cancel := queryContext(ctx)
+	ctx, span := startQuerySpan(ctx, query)
+	rows, err := db.Query(ctx, query, args...)
+	if err != nil {
+		endQuerySpan(span, err)
+		cancel()
+		return nil, err
+	}
+	return &queryRows{Rows: rows, span: span, cancel: cancel}, nil
+}
+
+// ExecuteQueryRowContext runs a query returning at most one row on db, the query ends with Scan
+// pgx.ErrNoRows is not recorded as a failure on the span
+func ExecuteQueryRowContext(ctx context.Context, db Querier, query string, args ...interface{}) pgx.Row {
+	ctx, cancel := queryContext(ctx)
+	ctx, span := startQuerySpan(ctx, query)
+	return &queryRow{row: db.QueryRow(ctx, query, args...), span: span, cancel: cancel}
+}
+
+// ExecuteNonQueryContext runs a command (insert, update, delete) on db
+func ExecuteNonQueryContext(ctx context.Context, db Querier, query string, args ...interface{}) (pgconn.CommandTag, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+	ctx, span := startQuerySpan(ctx, query)
+	commandTag, err := db.Exec(ctx, query, args...)
+	endQuerySpan(span, err)
+	return commandTag, err
+}
+
+// queryContext applies the default per query timeout (DB_QUERY_TIMEOUT) to ctx, 0 disables it
+// a deadline already on ctx that is sooner still wins
+func queryContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if timeout := config.Get().Database.QueryTimeout; timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return ctx, func() {}
+}
+
+// queryRows ends the query span and releases the timeout once the rows are done with
+type queryRows struct {
+	pgx.Rows
+	span   trace.Span
+	cancel context.CancelFunc
+	once   sync.Once
+}
+
+// Next reads the next row, after the last one the query is finished
+func (r *queryRows) Next() bool {
+	if r.Rows.Next() {
+		return true
+	}
+	r.finish()
+	return false
+}
+
+// Close closes the rows and finishes the query
+func (r *queryRows) Close() {
+	r.Rows.Close()
+	r.finish()
+}
+
+func (r *queryRows) finish() {
+	r.once.Do(func() {
+		endQuerySpan(r.span, r.Rows.Err())
+		r.cancel()
+	})
+}
+
+// queryRow ends the query span and releases the timeout once the row is scanned
+type queryRow struct {
+	row    pgx.Row
+	span   trace.Span
+	cancel context.CancelFunc
+}
+
+// Scan reads the row into dest and finishes the query
+func (r *queryRow) Scan(dest ...interface{}) error {
+	err := r.row.Scan(dest...)
+	if errors.Is(err, pgx.ErrNoRows) {
+		endQuerySpan(r.span, nil)
+	} else {
+		endQuerySpan(r.span, err)
+	}
+	r.cancel()
+	return err
+}
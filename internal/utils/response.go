@@ -5,6 +5,8 @@
 package utils
 
 import (
+	"encoding/json"
+	"feast-friends-api/pkg/helpers"
 	"feast-friends-api/pkg/logger"
 	"math"
 	"net/http"
@@ -35,6 +37,25 @@ func ErrorResponse(message string, err error, statusCode int) map[string]interfa
 	}
 }
 
+//this func formats a validation error JSON response (422)
+// it includes an errors map with a message per invalid field so clients can show them next to inputs
+func ValidationErrorResponse(err error) map[string]interface{} {
+	return FieldErrorsResponse("Validation failed", helpers.ValidationErrors(err))
+}
+
+//this func formats a 422 JSON response from a map of field -> message
+// it is used for checks the struct tags cant express (e.g. a date in the future)
+func FieldErrorsResponse(message string, fields map[string]string) map[string]interface{} {
+	logger.Error("validation error response : %v", fields)
+
+	return map[string]interface{}{
+		"status" : "error",
+		"message" : message,
+		"code" : http.StatusText(http.StatusUnprocessableEntity),
+		"errors" : fields,
+	}
+}
+
 //this func formats a successful JSON response without message
 // it is used for paginated responses
 // includes meta info like total count, page, limit and total pages
@@ -59,3 +80,30 @@ func PaginatedResponse(message string, data interface{}, totalCount, page, limit
 	}
 }
 
+//this func formats a successful JSON response for cursor (keyset) pagination
+// meta keeps the same limit key as PaginatedResponse plus next_cursor and has_more
+// next_cursor is empty when there are no more items
+func CursorPaginatedResponse(message string, data interface{}, limit int, nextCursor string) map[string]interface{} {
+	logger.Info("cursor paginated response : limit %d has_more %v", limit, nextCursor != "")
+
+	return map[string]interface{}{
+		"status" : "success",
+		"message" : message,
+		"data" : data,
+		"meta" : map[string]interface{}{
+			"limit" : limit,
+			"next_cursor" : nextCursor,
+			"has_more" : nextCursor != "",
+		},
+	}
+}
+
+// this func writes any response body as JSON with the given status code
+// handlers use it together with the response helpers above
+func WriteJSON(w http.ResponseWriter, statusCode int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		logger.Error("failed to encode JSON response : %v", err)
+	}
+}
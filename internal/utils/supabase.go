@@ -11,9 +11,13 @@ package utils
 //set importing necessary packages
 import (
 	"context"
+	"errors"
 	"feast-friends-api/internal/config"
 	"feast-friends-api/pkg/logger"
 	"fmt"
+	"io"
+	"net/http"
+	"strings"
 	"time"
 
 	"github.com/jackc/pgconn"
@@ -90,8 +94,9 @@ func Connection() error {
 // ExecuteQuery executes a query and returns the resulting rows
 // it uses interface{} to accept any type of arguments
 // it returns pgx.Rows and error if any
+// it runs on the global pool without a request context, use ExecuteQueryContext when one is at hand
 func ExecuteQuery(query string, args ...interface{}) (pgx.Rows, error) {
-	rows, err := DB.Query(context.Background(), query, args...)
+	rows, err := ExecuteQueryContext(context.Background(), DB, query, args...)
 	if err != nil {
 		logger.Error("query execution failed:%s : %v", query, err)
 		return nil, err
@@ -103,7 +108,7 @@ func ExecuteQuery(query string, args ...interface{}) (pgx.Rows, error) {
 // commandTag contains info about the executed command
 // tells rows affected and command that was executed)
 func ExecuteNonQuery(query string, args ...interface{}) (pgconn.CommandTag, error) {
-	commandTag, err := DB.Exec(context.Background(), query, args...)
+	commandTag, err := ExecuteNonQueryContext(context.Background(), DB, query, args...)
 	if err != nil {
 		logger.Error("non-query failed: %s : %v", query, err)
 	}
@@ -112,7 +117,7 @@ func ExecuteNonQuery(query string, args ...interface{}) (pgconn.CommandTag, erro
 
 // checks if supabase connection is alive 
 func SupabaseCheck() error {
-	_, err := SupabaseClient.Auth.GetUser()
+	err := PingSupabase(context.Background())
 	if err != nil {
 		logger.Error("Supabase health check failed : %v", err)
 		return err
@@ -123,7 +128,7 @@ func SupabaseCheck() error {
 
 // checks if db connection is alive
 func DBCheck() error {
-	if err := DB.Ping(context.Background()); err != nil {
+	if err := PingDB(context.Background()); err != nil {
 		logger.Error("Database health check failed : %v", err)
 		return err
 	}
@@ -131,6 +136,44 @@ func DBCheck() error {
 	return nil
 }
 
+// supabaseHealthClient calls the gotrue health endpoint, ctx usually ends the request first
+var supabaseHealthClient = &http.Client{Timeout: 5 * time.Second}
+
+// PingSupabase is SupabaseCheck without the logging, the readiness probe calls it on every refresh.
+// it asks GoTrue /health which needs no user token (GetUser always fails without one) and gives up when ctx is done
+func PingSupabase(ctx context.Context) error {
+	authURL := config.Get().Supabase.AuthURL
+	if authURL == "" {
+		return errors.New("supabase auth url not configured")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(authURL, "/")+"/health", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("apikey", config.Get().Supabase.AKey)
+
+	resp, err := supabaseHealthClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("supabase auth health check returned %s", resp.Status)
+	}
+	return nil
+}
+
+// PingDB is DBCheck without the logging, it gives up when ctx is done
+func PingDB(ctx context.Context) error {
+	if DB == nil {
+		return errors.New("db pool not connected")
+	}
+	return DB.Ping(ctx)
+}
+
 //function to close connections 
 func CloseConnections() {
 	if DB != nil {
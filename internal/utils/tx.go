@@ -0,0 +1,95 @@
+// tx.go runs functions inside a database transaction
+// the transaction is committed when the function returns nil and rolled back when it returns
+// an error or panics, serialization failures and deadlocks are retried with a short backoff
+
+package utils
+
+import (
+	"context"
+	"errors"
+	"feast-friends-api/internal/config"
+	"feast-friends-api/pkg/logger"
+	"math/rand"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// postgres error codes of transactions that failed because of another one, running them again usually works
+const (
+	serializationFailure = "40001"
+	deadlockDetected     = "40P01"
+)
+
+// txBaseBackoff is the wait before the second attempt, it doubles for each attempt after
+const txBaseBackoff = 20 * time.Millisecond
+
+// TxStarter begins transactions, *pgxpool.Pool and *pgxpool.Conn implement it
+type TxStarter interface {
+	BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error)
+}
+
+// WithTx runs fn in a read committed transaction on db, see WithTxOptions
+func WithTx(ctx context.Context, db TxStarter, fn func(tx pgx.Tx) error) error {
+	return WithTxOptions(ctx, db, pgx.TxOptions{}, fn)
+}
+
+// WithTxOptions runs fn in a transaction on db with the given options (e.g. serializable isolation).
+// fn is called again in a new transaction when postgres aborts it with a serialization failure or a
+// deadlock, up to DB_TX_MAX_ATTEMPTS times, so it must not have side effects outside of tx and has
+// to reset whatever it collects for the caller. Other errors of fn are returned as they are after the rollback.
+func WithTxOptions(ctx context.Context, db TxStarter, opts pgx.TxOptions, fn func(tx pgx.Tx) error) error {
+	attempts := config.Get().Database.TxMaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	ctx, span := dbTracer.Start(ctx, "transaction")
+	var err error
+retry:
+	for attempt := 1; attempt <= attempts; attempt++ {
+		span.SetAttributes(attribute.Int("db.transaction.attempts", attempt))
+		err = runTx(ctx, db, opts, fn)
+		if err == nil || !retryableTxError(err) || attempt == attempts {
+			break
+		}
+
+		backoff := txBaseBackoff << (attempt - 1)
+		backoff += time.Duration(rand.Int63n(int64(backoff))) // jitter so the conflicting transactions do not collide again
+		logger.WarnContext(ctx, "transaction attempt %d/%d failed, retrying in %s: %v", attempt, attempts, backoff, err)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			err = ctx.Err()
+			break retry
+		}
+	}
+	endQuerySpan(span, err)
+	return err
+}
+
+// runTx runs fn in one transaction, it commits when fn returns nil and rolls back otherwise, also when fn panics
+func runTx(ctx context.Context, db TxStarter, opts pgx.TxOptions, fn func(tx pgx.Tx) error) error {
+	tx, err := db.BeginTx(ctx, opts)
+	if err != nil {
+		return err
+	}
+	// a no-op once the transaction is committed
+	defer tx.Rollback(ctx)
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// retryableTxError reports whether err is a serialization failure or a deadlock
+func retryableTxError(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return pgErr.Code == serializationFailure || pgErr.Code == deadlockDetected
+}
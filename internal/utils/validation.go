@@ -48,19 +48,39 @@ func EmailIsValid(email string) bool {
 // this func checks for the pasword strength
 //password is only valid if it contains at least 8 characters, one uppercase letter, one special character and one digit
 func PasswordStrength(password string) bool {
-	password = strings.TrimSpace(password) // sanitize input making sure no html tags are present
-	if len(password)<8 {
-		return false
-	}
+	return len(PasswordRuleViolations(password)) == 0
+}
 
-	uppercase := regexp.MustCompile(`[A-Z]`)
-	lowercase := regexp.MustCompile(`[a-z]`)
-	specialChar := regexp.MustCompile(`[!@#$&*]`)
-	digit := regexp.MustCompile(`[0-9]`)
+// PasswordRule is one password requirement, the code is stable so clients can translate the message
+type PasswordRule struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
 
-	if  !uppercase.MatchString(password) || !specialChar.MatchString(password) || !digit.MatchString(password) ||!lowercase.MatchString(password) { 
-		return false /// we set the mimimum lenght of the password to 8
-	}
+// passwordRules are checked in this order by PasswordRuleViolations
+var passwordRules = []struct {
+	rule  PasswordRule
+	valid func(string) bool
+}{
+	{PasswordRule{"min_length", "must be at least 8 characters"}, func(p string) bool { return len(p) >= 8 }},
+	// supabase hashes passwords with bcrypt which ignores everything after 72 bytes
+	{PasswordRule{"max_length", "must be at most 72 characters"}, func(p string) bool { return len(p) <= 72 }},
+	{PasswordRule{"uppercase", "must contain an uppercase letter"}, regexp.MustCompile(`[A-Z]`).MatchString},
+	{PasswordRule{"lowercase", "must contain a lowercase letter"}, regexp.MustCompile(`[a-z]`).MatchString},
+	{PasswordRule{"digit", "must contain a digit"}, regexp.MustCompile(`[0-9]`).MatchString},
+	{PasswordRule{"special_character", "must contain one of !@#$&*"}, regexp.MustCompile(`[!@#$&*]`).MatchString},
+}
 
-	return true
-}
\ No newline at end of file
+// this func returns every rule the password breaks, an empty slice means the password is strong enough
+// it is what PasswordStrength uses so both always agree
+func PasswordRuleViolations(password string) []PasswordRule {
+	password = strings.TrimSpace(password) // sanitize input the same way PasswordStrength always did
+
+	violations := []PasswordRule{}
+	for _, r := range passwordRules {
+		if !r.valid(password) {
+			violations = append(violations, r.rule)
+		}
+	}
+	return violations
+}
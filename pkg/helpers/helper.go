@@ -1,6 +1,10 @@
 package helpers
 
 import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
 	"time"
 
 	"github.com/go-playground/validator/v10"
@@ -10,6 +14,15 @@ var validate *validator.Validate
 
 func init() {
 	validate = validator.New()
+
+	// report fields by their json name so api clients see the same keys they sent
+	validate.RegisterTagNameFunc(func(fld reflect.StructField) string {
+		name := strings.SplitN(fld.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
 }
 
 // ValidateStruct performs validation on the given struct based on validate tags.
@@ -19,6 +32,60 @@ func ValidateStruct(s interface{}) error {
 	return validate.Struct(s)
 }
 
+// ValidationErrors turns the error returned by ValidateStruct into a map of field -> message.
+// Field keys are the json path without the struct name (e.g. "recipe.ingredients[0].name").
+// Returns nil if err is not a validation error.
+func ValidationErrors(err error) map[string]string {
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return nil
+	}
+
+	fields := make(map[string]string, len(verrs))
+	for _, fe := range verrs {
+		key := fe.Namespace()
+		if i := strings.Index(key, "."); i >= 0 {
+			key = key[i+1:] // drop the root struct name
+		}
+		fields[key] = validationMessage(fe)
+	}
+	return fields
+}
+
+// validationMessage returns a human readable message for a single failed rule
+func validationMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "min":
+		if fe.Kind() == reflect.String {
+			return fmt.Sprintf("must be at least %s characters", fe.Param())
+		}
+		if fe.Kind() == reflect.Slice {
+			return fmt.Sprintf("must contain at least %s items", fe.Param())
+		}
+		return fmt.Sprintf("must be at least %s", fe.Param())
+	case "max":
+		if fe.Kind() == reflect.String {
+			return fmt.Sprintf("must be at most %s characters", fe.Param())
+		}
+		if fe.Kind() == reflect.Slice {
+			return fmt.Sprintf("must contain at most %s items", fe.Param())
+		}
+		return fmt.Sprintf("must be at most %s", fe.Param())
+	case "email":
+		return "must be a valid email address"
+	case "url":
+		return "must be a valid URL"
+	case "alphanum":
+		return "must only contain letters and numbers"
+	case "oneof":
+		return fmt.Sprintf("must be one of: %s", fe.Param())
+	default:
+		return fmt.Sprintf("failed the %q rule", fe.Tag())
+	}
+}
+
 // FormatTime converts an RFC3339 formatted time string to a more readable format.
 // This helper function is used to format timestamps in API models.
 // Input: CreatedAt - string in RFC3339 format (e.g. "2006-01-02T15:04:05Z07:00")
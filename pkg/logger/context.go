@@ -0,0 +1,66 @@
+// context.go ties log entries to the request they belong to
+// middleware.Logs stores the request id in the request context, the *Context functions and
+// FromContext add it to the entry so every line logged while serving a request can be found by id
+
+package logger
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RequestIDField is the field the request id is logged under
+const RequestIDField = "requestID"
+
+// requestIDKey is the context key of the request id
+type requestIDKey struct{}
+
+// ContextWithRequestID returns a copy of ctx carrying the request id
+func ContextWithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestIDFromContext returns the request id stored by ContextWithRequestID, "" when there is none
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// FromContext returns an entry that logs the request id of ctx, use it to add more fields
+func FromContext(ctx context.Context) *logrus.Entry {
+	return Log.WithContext(ctx)
+}
+
+// the wrappers below are Info, Error, Debug and Warn for code that has the request context
+func InfoContext(ctx context.Context, format string, args ...interface{}) {
+	Log.WithContext(ctx).Infof(format, args...)
+}
+func ErrorContext(ctx context.Context, format string, args ...interface{}) {
+	Log.WithContext(ctx).Errorf(format, args...)
+}
+func DebugContext(ctx context.Context, format string, args ...interface{}) {
+	Log.WithContext(ctx).Debugf(format, args...)
+}
+func WarnContext(ctx context.Context, format string, args ...interface{}) {
+	Log.WithContext(ctx).Warnf(format, args...)
+}
+
+// contextHook adds the request id of the entry context, it covers every entry created with WithContext
+type contextHook struct{}
+
+// Levels makes the hook run for every level
+func (contextHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire adds the request id field when the entry has a context with one
+func (contextHook) Fire(entry *logrus.Entry) error {
+	if entry.Context == nil {
+		return nil
+	}
+	if id := RequestIDFromContext(entry.Context); id != "" {
+		entry.Data[RequestIDField] = id
+	}
+	return nil
+}
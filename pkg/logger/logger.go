@@ -83,6 +83,9 @@ func init() {
 	// set final level 
 	Log.SetLevel(level)
 
+	// entries logged with a request context get its request id
+	Log.AddHook(contextHook{})
+
 	Log.WithFields(logrus.Fields{
 		"env" : env,
 		"level": level,
@@ -0,0 +1,77 @@
+-- Keeps the denormalized social counters in sync with the likes, follows and posts tables.
+-- Counters are maintained by triggers so every write path (api, dashboard, scripts) stays consistent.
+
+-- 1. Profile counters
+ALTER TABLE public.profiles
+    ADD COLUMN followers_count INT NOT NULL DEFAULT 0 CHECK (followers_count >= 0),
+    ADD COLUMN following_count INT NOT NULL DEFAULT 0 CHECK (following_count >= 0),
+    ADD COLUMN posts_count INT NOT NULL DEFAULT 0 CHECK (posts_count >= 0);
+
+-- Backfill from the existing rows
+UPDATE public.profiles p SET
+    followers_count = (SELECT count(*) FROM public.follows f WHERE f.following_id = p.id),
+    following_count = (SELECT count(*) FROM public.follows f WHERE f.follower_id = p.id),
+    posts_count = (SELECT count(*) FROM public.posts po WHERE po.user_id = p.id);
+
+UPDATE public.posts po SET
+    likes_count = (SELECT count(*) FROM public.likes l WHERE l.post_id = po.id);
+
+-- 2. likes -> posts.likes_count
+CREATE OR REPLACE FUNCTION public.handle_like_change()
+RETURNS TRIGGER AS $$
+BEGIN
+  IF TG_OP = 'INSERT' THEN
+    UPDATE public.posts SET likes_count = likes_count + 1 WHERE id = NEW.post_id;
+    RETURN NEW;
+  ELSE
+    UPDATE public.posts SET likes_count = GREATEST(likes_count - 1, 0) WHERE id = OLD.post_id;
+    RETURN OLD;
+  END IF;
+END;
+$$ LANGUAGE plpgsql SECURITY DEFINER;
+
+CREATE TRIGGER on_likes_changed
+  AFTER INSERT OR DELETE ON public.likes
+  FOR EACH ROW EXECUTE PROCEDURE public.handle_like_change();
+
+-- 3. follows -> profiles.followers_count / following_count
+CREATE OR REPLACE FUNCTION public.handle_follow_change()
+RETURNS TRIGGER AS $$
+BEGIN
+  IF TG_OP = 'INSERT' THEN
+    UPDATE public.profiles SET followers_count = followers_count + 1 WHERE id = NEW.following_id;
+    UPDATE public.profiles SET following_count = following_count + 1 WHERE id = NEW.follower_id;
+    RETURN NEW;
+  ELSE
+    UPDATE public.profiles SET followers_count = GREATEST(followers_count - 1, 0) WHERE id = OLD.following_id;
+    UPDATE public.profiles SET following_count = GREATEST(following_count - 1, 0) WHERE id = OLD.follower_id;
+    RETURN OLD;
+  END IF;
+END;
+$$ LANGUAGE plpgsql SECURITY DEFINER;
+
+CREATE TRIGGER on_follows_changed
+  AFTER INSERT OR DELETE ON public.follows
+  FOR EACH ROW EXECUTE PROCEDURE public.handle_follow_change();
+
+-- 4. posts -> profiles.posts_count
+CREATE OR REPLACE FUNCTION public.handle_post_change()
+RETURNS TRIGGER AS $$
+BEGIN
+  IF TG_OP = 'INSERT' THEN
+    UPDATE public.profiles SET posts_count = posts_count + 1 WHERE id = NEW.user_id;
+    RETURN NEW;
+  ELSE
+    UPDATE public.profiles SET posts_count = GREATEST(posts_count - 1, 0) WHERE id = OLD.user_id;
+    RETURN OLD;
+  END IF;
+END;
+$$ LANGUAGE plpgsql SECURITY DEFINER;
+
+CREATE TRIGGER on_posts_changed
+  AFTER INSERT OR DELETE ON public.posts
+  FOR EACH ROW EXECUTE PROCEDURE public.handle_post_change();
+
+-- Indexes for the follower/following list queries
+CREATE INDEX IF NOT EXISTS follows_following_id_idx ON public.follows (following_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS follows_follower_id_idx ON public.follows (follower_id, created_at DESC);
@@ -0,0 +1,56 @@
+-- Threaded comments: replies point at their parent comment, deletes are soft so
+-- the thread keeps its shape, and posts.comments_count only counts visible comments.
+
+-- 1. Thread columns
+ALTER TABLE public.comments
+    ADD COLUMN parent_id UUID REFERENCES public.comments(id) ON DELETE CASCADE,
+    ADD COLUMN deleted_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS comments_post_root_idx ON public.comments (post_id, created_at, id) WHERE parent_id IS NULL;
+CREATE INDEX IF NOT EXISTS comments_parent_idx ON public.comments (parent_id, created_at, id);
+
+-- 2. A reply must belong to the same post as its parent
+CREATE OR REPLACE FUNCTION public.check_comment_parent()
+RETURNS TRIGGER AS $$
+BEGIN
+  IF NEW.parent_id IS NOT NULL AND NOT EXISTS (
+    SELECT 1 FROM public.comments WHERE id = NEW.parent_id AND post_id = NEW.post_id
+  ) THEN
+    RAISE EXCEPTION 'parent comment % does not belong to post %', NEW.parent_id, NEW.post_id
+      USING ERRCODE = 'foreign_key_violation';
+  END IF;
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER on_comments_parent_check
+  BEFORE INSERT ON public.comments
+  FOR EACH ROW EXECUTE PROCEDURE public.check_comment_parent();
+
+-- 3. comments -> posts.comments_count (soft deleted comments are not counted)
+UPDATE public.posts po SET
+    comments_count = (SELECT count(*) FROM public.comments c WHERE c.post_id = po.id AND c.deleted_at IS NULL);
+
+CREATE OR REPLACE FUNCTION public.handle_comment_change()
+RETURNS TRIGGER AS $$
+BEGIN
+  IF TG_OP = 'INSERT' THEN
+    UPDATE public.posts SET comments_count = comments_count + 1 WHERE id = NEW.post_id;
+    RETURN NEW;
+  ELSIF TG_OP = 'UPDATE' THEN
+    IF OLD.deleted_at IS NULL AND NEW.deleted_at IS NOT NULL THEN
+      UPDATE public.posts SET comments_count = GREATEST(comments_count - 1, 0) WHERE id = NEW.post_id;
+    END IF;
+    RETURN NEW;
+  ELSE
+    IF OLD.deleted_at IS NULL THEN
+      UPDATE public.posts SET comments_count = GREATEST(comments_count - 1, 0) WHERE id = OLD.post_id;
+    END IF;
+    RETURN OLD;
+  END IF;
+END;
+$$ LANGUAGE plpgsql SECURITY DEFINER;
+
+CREATE TRIGGER on_comments_changed
+  AFTER INSERT OR UPDATE OF deleted_at OR DELETE ON public.comments
+  FOR EACH ROW EXECUTE PROCEDURE public.handle_comment_change();
@@ -0,0 +1,25 @@
+-- Event lifecycle and RSVP capacity.
+-- current_attendees is maintained by the api inside the RSVP transaction (the event row is
+-- locked with SELECT ... FOR UPDATE) and the CHECK below is a last line of defence against overbooking.
+
+ALTER TABLE public.events
+    ADD COLUMN current_attendees INT NOT NULL DEFAULT 0 CHECK (current_attendees >= 0),
+    ADD COLUMN cancelled_at TIMESTAMPTZ;
+
+-- Backfill from the existing RSVPs
+UPDATE public.events e SET
+    current_attendees = (SELECT count(*) FROM public.event_rsvps r WHERE r.event_id = e.id AND r.status = 'attending');
+
+ALTER TABLE public.events
+    ADD CONSTRAINT events_capacity_check CHECK (max_attendees IS NULL OR current_attendees <= max_attendees);
+
+-- RSVPs remember when they last changed
+ALTER TABLE public.event_rsvps
+    ADD COLUMN updated_at TIMESTAMPTZ DEFAULT now();
+
+CREATE TRIGGER on_event_rsvps_updated
+  BEFORE UPDATE ON public.event_rsvps
+  FOR EACH ROW EXECUTE PROCEDURE public.handle_updated_at();
+
+CREATE INDEX IF NOT EXISTS events_upcoming_idx ON public.events (event_date) WHERE cancelled_at IS NULL;
+CREATE INDEX IF NOT EXISTS event_rsvps_event_status_idx ON public.event_rsvps (event_id, status, created_at);
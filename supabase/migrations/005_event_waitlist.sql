@@ -0,0 +1,14 @@
+-- Event waitlist: RSVPs to a full event are queued as 'waitlisted' in waitlist_position order
+-- and promoted to 'attending' by the api when a spot frees up.
+
+ALTER TABLE public.event_rsvps DROP CONSTRAINT IF EXISTS event_rsvps_status_check;
+ALTER TABLE public.event_rsvps
+    ADD CONSTRAINT event_rsvps_status_check CHECK (status IN ('attending', 'maybe', 'not_attending', 'waitlisted'));
+
+ALTER TABLE public.event_rsvps
+    ADD COLUMN waitlist_position INT,
+    ADD CONSTRAINT event_rsvps_waitlist_position_check
+        CHECK ((status = 'waitlisted') = (waitlist_position IS NOT NULL));
+
+CREATE INDEX IF NOT EXISTS event_rsvps_waitlist_idx ON public.event_rsvps (event_id, waitlist_position)
+    WHERE status = 'waitlisted';
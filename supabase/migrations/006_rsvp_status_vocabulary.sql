@@ -0,0 +1,13 @@
+-- RSVP statuses use the same words as the api: going, maybe, cancelled and waitlisted.
+-- The first schema stored 'attending' and 'not_attending', existing rows are rewritten below.
+-- The api still accepts the old spellings on input and when reading rows.
+
+ALTER TABLE public.event_rsvps DROP CONSTRAINT IF EXISTS event_rsvps_status_check;
+
+-- Backfill
+UPDATE public.event_rsvps SET status = 'going' WHERE status = 'attending';
+UPDATE public.event_rsvps SET status = 'cancelled' WHERE status = 'not_attending';
+
+ALTER TABLE public.event_rsvps
+    ALTER COLUMN status SET DEFAULT 'going',
+    ADD CONSTRAINT event_rsvps_status_check CHECK (status IN ('going', 'maybe', 'cancelled', 'waitlisted'));
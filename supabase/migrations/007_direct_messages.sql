@@ -0,0 +1,27 @@
+-- Direct messaging.
+-- Messages get a type (text, image or video, media messages carry the url as content) and
+-- conversations.last_message_at is bumped by a trigger so the inbox can be sorted by recent activity.
+
+ALTER TABLE public.messages
+    ADD COLUMN message_type TEXT NOT NULL DEFAULT 'text' CHECK (message_type IN ('text', 'image', 'video'));
+
+CREATE OR REPLACE FUNCTION public.handle_new_message()
+RETURNS TRIGGER AS $$
+BEGIN
+  UPDATE public.conversations SET last_message_at = NEW.created_at WHERE id = NEW.conversation_id;
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql SECURITY DEFINER;
+
+CREATE TRIGGER on_message_created
+  AFTER INSERT ON public.messages
+  FOR EACH ROW EXECUTE PROCEDURE public.handle_new_message();
+
+-- Backfill
+UPDATE public.conversations c SET
+    last_message_at = (SELECT max(m.created_at) FROM public.messages m WHERE m.conversation_id = c.id);
+
+CREATE INDEX IF NOT EXISTS messages_conversation_created_idx ON public.messages (conversation_id, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS messages_unread_idx ON public.messages (conversation_id, sender_id) WHERE read_at IS NULL;
+CREATE INDEX IF NOT EXISTS conversations_participant_1_idx ON public.conversations (participant_1, last_message_at DESC);
+CREATE INDEX IF NOT EXISTS conversations_participant_2_idx ON public.conversations (participant_2, last_message_at DESC);
@@ -0,0 +1,22 @@
+-- Session tokens issued by the api.
+-- Each login starts a refresh token family, refreshing replaces current_jti so a replayed
+-- (already rotated) refresh token is detected and revokes the family.
+-- revoked_tokens is the list of access tokens logged out before they expire, checked by AuthMiddleware.
+
+CREATE TABLE IF NOT EXISTS public.auth_sessions (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES public.profiles(id) ON DELETE CASCADE,
+    current_jti UUID NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT now() NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS auth_sessions_user_idx ON public.auth_sessions (user_id) WHERE revoked_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS public.revoked_tokens (
+    token_id TEXT PRIMARY KEY,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS revoked_tokens_expires_idx ON public.revoked_tokens (expires_at);
@@ -0,0 +1,10 @@
+-- Roles of each profile, checked by the RequireRole middleware.
+-- Every profile is a 'user', moderators and admins are granted by an admin through the api.
+-- Tokens issued by the api carry the roles, they are re-read from here on login and refresh.
+
+ALTER TABLE public.profiles
+    ADD COLUMN IF NOT EXISTS roles TEXT[] NOT NULL DEFAULT ARRAY['user']::TEXT[];
+
+ALTER TABLE public.profiles
+    ADD CONSTRAINT profiles_roles_check
+    CHECK (roles <@ ARRAY['user', 'moderator', 'admin']::TEXT[] AND 'user' = ANY (roles));
@@ -0,0 +1,12 @@
+-- Edit history of comments.
+-- Every edit keeps the content it replaced, written by the api in the same transaction as the edit.
+-- Soft deleting a comment removes its history along with its content.
+
+CREATE TABLE IF NOT EXISTS public.comment_edits (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    comment_id UUID NOT NULL REFERENCES public.comments(id) ON DELETE CASCADE,
+    content TEXT NOT NULL,
+    edited_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS comment_edits_comment_idx ON public.comment_edits (comment_id, edited_at, id);